@@ -0,0 +1,491 @@
+package csv
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldBinding is the resolved binding of one column to a struct field. A slice of bindings, one per column, can be
+// computed once with NewFieldBindings and reused across records and goroutines.
+type FieldBinding struct {
+	Header     string            // Header is the column header
+	Name       string            // Name is the name of the bound struct field
+	Index      []int             // Index is the index path of the bound field from the top-level struct; nil when the column is ignored
+	Type       reflect.Type      // Type is the type of the bound field
+	Codecs     []string          // Codecs are the cell encodings named in the field's csv tag (base64, gzip), decoded in order
+	TypeHint   reflect.Type      // TypeHint is the type parsed into an empty interface field, from FieldTypeHints or Schema; nil stores the raw string
+	TagOptions map[string]string // TagOptions are the options of the field's csv tag, e.g. "trim"; options without a value map to ""
+	Compose    []*Composition    // Compose are the composed fields the column is a source of, each shared by all of its sources
+}
+
+// Composition binds a field tagged with the compose option, as in `csv:"date,compose=year+month+day"`, to the
+// columns it is built from.
+type Composition struct {
+	Name    string       // Name is the name of the composed struct field
+	Index   []int        // Index is the index path of the composed field from the top-level struct
+	Type    reflect.Type // Type is the type of the composed field
+	Sources []int        // Sources are the columns the field is composed from, in the order the tag names them
+}
+
+// last returns the last source column, after which the field is composed.
+func (c *Composition) last() int {
+	last := -1
+	for _, col := range c.Sources {
+		if col > last {
+			last = col
+		}
+	}
+	return last
+}
+
+// NewFieldBindings resolves each header to a field of T. Headers without a matching field are an error unless
+// IgnoreUnknownFields is set, in which case their binding has a nil Index.
+func NewFieldBindings[T any](options *Options, headers []string) ([]FieldBinding, error) {
+	return newFieldBindings(normalizeOptions(options), reflect.TypeOf((*T)(nil)).Elem(), headers)
+}
+
+// ResolveField reports the name of the field of T that header binds to under options, applying the same field name,
+// struct tag, Schema and HeaderSynonyms resolution as NewFieldBindings. ok is false when the header matches no field.
+func ResolveField[T any](options *Options, header string) (fieldName string, ok bool) {
+	resolved := *normalizeOptions(options)
+	resolved.IgnoreUnknownFields = true
+	mapping, err := newFieldBindings(&resolved, reflect.TypeOf((*T)(nil)).Elem(), []string{header})
+	if err != nil || mapping[0].Index == nil {
+		return "", false
+	}
+	return mapping[0].Name, true
+}
+
+// ParseHeader reads the header line of content and returns the name of the field of T each header binds to.
+// Unresolved headers are an error unless IgnoreUnknownFields is set, in which case they are omitted.
+func ParseHeader[T any](options *Options, content string) (map[string]string, error) {
+	options = normalizeOptions(options)
+	headers, err := readHeader(options, newRecordReader(options, strings.NewReader(content)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv: %w", err)
+	}
+
+	mapping, err := newFieldBindings(options, reflect.TypeOf((*T)(nil)).Elem(), headers)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string, len(mapping))
+	for _, b := range mapping {
+		if b.Index != nil {
+			fields[b.Header] = b.Name
+		}
+	}
+	return fields, nil
+}
+
+// newFieldBindings resolves each header to a field of the struct type rt. options must already be normalized.
+func newFieldBindings(options *Options, rt reflect.Type, headers []string) ([]FieldBinding, error) {
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %s", rt.Kind())
+	}
+
+	composed := composeFields(rt)
+	sources := map[string]bool{}
+	for _, c := range composed {
+		for _, source := range c.sources {
+			sources[source] = true
+		}
+	}
+
+	mapping := make([]FieldBinding, len(headers))
+	for i, header := range headers {
+		mapping[i].Header = header
+		mapping[i].TypeHint = options.FieldTypeHints[header]
+
+		var sf reflect.StructField
+		var ok bool
+		if column, found := options.Schema.column(header); found {
+			sf, ok, _ = resolveField(&Options{UseFieldNames: true}, rt, column.Field)
+			if !ok {
+				return nil, fmt.Errorf("schema field %s for header %s not found", column.Field, header)
+			}
+			if column.Type != "" {
+				mapping[i].TypeHint = schemaTypes[column.Type]
+			}
+		} else {
+			var err error
+			sf, ok, err = resolveField(options, rt, header)
+			if err != nil {
+				return nil, fmt.Errorf("error getting field name from struct tag: %w", err)
+			}
+			if name, found := synonymField(options, header); found && !ok {
+				sf, ok = rt.FieldByName(name)
+			}
+		}
+		if !ok {
+			if !options.IgnoreUnknownFields && !sources[header] {
+				return nil, fmt.Errorf("unknown field: %s", header)
+			}
+			continue
+		}
+		if !settable(rt, sf.Index) {
+			if !options.IgnoreUnknownFields {
+				return nil, fmt.Errorf("field %s for header %s is unexported", sf.Name, header)
+			}
+			continue
+		}
+		mapping[i].Name = sf.Name
+		mapping[i].Index = sf.Index
+		mapping[i].Type = sf.Type
+		mapping[i].Codecs = tagCodecs(sf.Tag)
+		mapping[i].TagOptions = tagOptions(sf.Tag)
+	}
+
+	for _, c := range composed {
+		comp := &Composition{Name: c.field.Name, Index: c.field.Index, Type: c.field.Type}
+		for _, source := range c.sources {
+			col := -1
+			for i, header := range headers {
+				if header == source {
+					col = i
+					break
+				}
+			}
+			if col < 0 {
+				if options.IgnoreUnknownFields {
+					comp = nil
+					break
+				}
+				return nil, fmt.Errorf("compose source %s for field %s not found in header", source, c.field.Name)
+			}
+			comp.Sources = append(comp.Sources, col)
+		}
+		if comp == nil {
+			continue
+		}
+		for _, col := range comp.Sources {
+			mapping[col].Compose = append(mapping[col].Compose, comp)
+		}
+	}
+	return mapping, nil
+}
+
+// composeField is a field tagged with the compose option and the headers of its source columns.
+type composeField struct {
+	field   reflect.StructField // field has the index path from the top-level struct
+	sources []string
+}
+
+// composeFields returns the fields of rt, including those of nested structs, tagged with the compose option.
+func composeFields(rt reflect.Type) []composeField {
+	var fields []composeField
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if sources, ok := tagOptions(f.Tag)["compose"]; ok && sources != "" {
+			fields = append(fields, composeField{field: f, sources: strings.Split(sources, "+")})
+			continue
+		}
+		if isNestedStruct(f.Type) {
+			for _, nested := range composeFields(f.Type) {
+				nested.field.Index = append([]int{i}, nested.field.Index...)
+				fields = append(fields, nested)
+			}
+		}
+	}
+	return fields
+}
+
+// compose sets the field of s that c composes from the values of its source columns in record, using the function
+// in ComposeFuncMap for the field or, for time.Time fields, composeDate.
+func compose(options *Options, c *Composition, record []string, s reflect.Value) error {
+	values := make([]string, len(c.Sources))
+	for k, col := range c.Sources {
+		values[k] = record[col]
+	}
+
+	f := s.FieldByIndex(c.Index)
+	var err error
+	if fn, ok := options.ComposeFuncMap[c.Name]; ok {
+		err = fn(&f, values)
+	} else if c.Type == reflect.TypeOf(time.Time{}) {
+		var t time.Time
+		t, err = composeDate(options, values)
+		if err == nil {
+			f.Set(reflect.ValueOf(t))
+		}
+	} else {
+		return fmt.Errorf("field %s has no compose function", c.Name)
+	}
+	if err != nil && !options.ignoreTypeError() {
+		return fmt.Errorf("field %s compose failed: %w", c.Name, err)
+	}
+	return nil
+}
+
+// rownumField is a field tagged with the rownum option, set to the record's ordinal instead of a column.
+type rownumField struct {
+	index []int
+	base  int64 // base is the ordinal of the first record, from rownum=1 for example (defaults to 0)
+}
+
+// rownumFields returns the integer fields of rt, including those of nested structs, tagged `csv:",rownum"` or
+// `csv:",rownum=1"`.
+func rownumFields(rt reflect.Type) []rownumField {
+	var fields []rownumField
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if isNestedStruct(f.Type) {
+			for _, nested := range rownumFields(f.Type) {
+				nested.index = append([]int{i}, nested.index...)
+				fields = append(fields, nested)
+			}
+			continue
+		}
+		base, ok := tagOptions(f.Tag)["rownum"]
+		if !ok || !isIntegerKind(f.Type.Kind()) {
+			continue
+		}
+		n, _ := strconv.ParseInt(base, 10, 64)
+		fields = append(fields, rownumField{index: []int{i}, base: n})
+	}
+	return fields
+}
+
+// setRownums sets the rownum fields of the struct value s for the record at the zero-based ordinal n.
+func setRownums(fields []rownumField, s reflect.Value, n int) {
+	for _, rf := range fields {
+		f := s.FieldByIndex(rf.index)
+		if f.CanInt() {
+			f.SetInt(rf.base + int64(n))
+		} else {
+			f.SetUint(uint64(rf.base + int64(n)))
+		}
+	}
+}
+
+// settable reports whether the field at index in rt can be set, that is whether it is exported and not reached
+// through an unexported named field. Fields promoted through unexported embedded structs are settable.
+func settable(rt reflect.Type, index []int) bool {
+	for i, j := range index {
+		if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		sf := rt.Field(j)
+		if sf.PkgPath != "" && (i == len(index)-1 || !sf.Anonymous) {
+			return false
+		}
+		rt = sf.Type
+	}
+	return true
+}
+
+// UnmarshalRecordMapped unmarshals a single record into a struct using bindings from NewFieldBindings.
+func UnmarshalRecordMapped[T any](options *Options, mapping []FieldBinding, record []string, v *T) error {
+	return unmarshalMapped(normalizeOptions(options), mapping, record, nil, reflect.ValueOf(v).Elem())
+}
+
+// unmarshalMapped unmarshals a single record into the struct value s. quoted, when non-nil, reports which empty
+// fields of record were quoted. options must already be normalized.
+func unmarshalMapped(options *Options, mapping []FieldBinding, record []string, quoted []bool, s reflect.Value) error {
+	if len(record) > len(mapping) {
+		return fmt.Errorf("record has %d fields but only %d headers", len(record), len(mapping))
+	}
+	for i := 0; i < len(record); i++ {
+		header := mapping[i].Header
+		if options.RejectControlChars {
+			if c, ok := findControlChar(record[i]); ok {
+				return fmt.Errorf("field %s contains disallowed character %U", header, c)
+			}
+		}
+
+		if mapping[i].Index == nil {
+			if options.Stats != nil && len(mapping[i].Compose) == 0 {
+				options.Stats.SkippedFields++
+			}
+			continue
+		}
+		f := s.FieldByIndex(mapping[i].Index)
+
+		value := record[i]
+		for _, null := range options.NullValues {
+			if value == null {
+				value = ""
+				break
+			}
+		}
+		if options.StripCR {
+			value = strings.TrimSuffix(value, "\r")
+		}
+		if cutset, ok := mapping[i].TagOptions["trim"]; ok {
+			value = strings.Trim(value, cutset)
+		}
+		if len(mapping[i].Codecs) > 0 {
+			decoded, err := decodeCell(value, mapping[i].Codecs)
+			if err != nil && !options.ignoreTypeError() {
+				return fmt.Errorf("field %s decode failed: %w", header, err)
+			}
+			if err == nil {
+				value = decoded
+			}
+		}
+		if options.Unescape == UnescapePercentEncoding {
+			unescaped, err := url.QueryUnescape(value)
+			if err != nil && !options.ignoreTypeError() {
+				return fmt.Errorf("field %s unescape failed: %w", header, err)
+			}
+			if err == nil {
+				value = unescaped
+			}
+		}
+
+		fv := f
+		if _, custom := options.CustomMarshallingFuncMap[f.Type().String()]; f.Kind() == reflect.Ptr && !custom {
+			if value == "" && (i >= len(quoted) || !quoted[i]) {
+				f.Set(reflect.Zero(f.Type()))
+				continue
+			}
+			if f.IsNil() {
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+			fv = f.Elem()
+		}
+
+		if fv.Kind() == reflect.Interface && fv.NumMethod() == 0 {
+			if hint := mapping[i].TypeHint; hint != nil {
+				hv := reflect.New(hint).Elem()
+				if err := unmarshalField(options, hv, header, value); err != nil {
+					return err
+				}
+				fv.Set(hv)
+			} else {
+				fv.Set(reflect.ValueOf(value))
+			}
+		} else if err := unmarshalField(options, fv, header, value); err != nil {
+			return err
+		}
+
+		if transform, ok := options.FieldTransformMap[mapping[i].Name]; ok {
+			if err := transform(f); err != nil && !options.ignoreTypeError() {
+				return fmt.Errorf("field %s transform failed: %w", header, err)
+			}
+		}
+	}
+	for i := 0; i < len(record); i++ {
+		for _, c := range mapping[i].Compose {
+			if c.last() != i {
+				continue
+			}
+			if err := compose(options, c, record, s); err != nil {
+				return err
+			}
+		}
+	}
+	if options.Stats != nil {
+		options.Stats.Rows++
+	}
+	return nil
+}
+
+// checkHeaderMatch reports an error when mapping leaves a header unbound or a bindable field of rt without a column.
+func checkHeaderMatch(options *Options, rt reflect.Type, mapping []FieldBinding) error {
+	bound := map[string]bool{}
+	var extra []string
+	for _, b := range mapping {
+		for _, c := range b.Compose {
+			bound[fmt.Sprint(c.Index)] = true
+		}
+		if b.Index == nil {
+			if len(b.Compose) == 0 {
+				extra = append(extra, b.Header)
+			}
+			continue
+		}
+		bound[fmt.Sprint(b.Index)] = true
+	}
+
+	var missing []string
+	headers, fields := marshalColumns(rt, "", options.UseStructTags)
+	for i, index := range fields {
+		if !bound[fmt.Sprint(index)] {
+			missing = append(missing, headers[i])
+		}
+	}
+
+	if len(missing) > 0 || len(extra) > 0 {
+		return fmt.Errorf("header does not match struct fields: missing %v, extra %v", missing, extra)
+	}
+	return nil
+}
+
+// synonymField returns the field name whose Options.HeaderSynonyms list contains header.
+func synonymField(options *Options, header string) (string, bool) {
+	for name, synonyms := range options.HeaderSynonyms {
+		for _, synonym := range synonyms {
+			if synonym == header {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveField finds the field of rt bound to header. A dotted header such as "Address.City" binds to a field of a
+// nested struct when no top-level field matches. The returned field's Index is the index path from rt.
+func resolveField(options *Options, rt reflect.Type, header string) (reflect.StructField, bool, error) {
+	sf, ok, err := lookupField(options, rt, header)
+	if err != nil || ok {
+		return sf, ok, err
+	}
+
+	name, rest, found := strings.Cut(header, ".")
+	if !found {
+		return reflect.StructField{}, false, nil
+	}
+	parent, ok, err := lookupField(options, rt, name)
+	if err != nil || !ok || !isNestedStruct(parent.Type) {
+		return reflect.StructField{}, false, err
+	}
+	sf, ok, err = resolveField(options, parent.Type, rest)
+	if err != nil || !ok {
+		return reflect.StructField{}, false, err
+	}
+	sf.Index = append(append([]int{}, parent.Index...), sf.Index...)
+	return sf, true, nil
+}
+
+// lookupField finds the field of rt named name, or tagged name when UseStructTags is set.
+func lookupField(options *Options, rt reflect.Type, name string) (reflect.StructField, bool, error) {
+	fieldName := name
+	if options.UseStructTags {
+		var err error
+		fieldName, err = getFieldNameFromStructTag(name, "csv", reflect.Zero(rt).Interface())
+		if err != nil || fieldName == "" {
+			return reflect.StructField{}, false, err
+		}
+	}
+	sf, ok := rt.FieldByName(fieldName)
+	return sf, ok, nil
+}
+
+// isNestedStruct reports whether t is a struct whose fields are flattened into dotted headers, as opposed to a
+// struct such as time.Time that is converted as a single value.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.String() != "time.Time"
+}
+
+// tagOptions parses the options of a field's csv tag, such as "trim=*". An option's value is everything after the
+// first "="; options are separated by commas, so values cannot contain one.
+func tagOptions(tag reflect.StructTag) map[string]string {
+	opts := strings.Split(tag.Get("csv"), ",")
+	m := make(map[string]string, len(opts)-1)
+	for _, opt := range opts[1:] {
+		key, value, _ := strings.Cut(opt, "=")
+		m[key] = value
+	}
+	return m
+}
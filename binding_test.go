@@ -0,0 +1,474 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUnescapePercentEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		cell    string
+		want    string
+		wantErr bool
+	}{
+		{"encoded comma", Options{Unescape: UnescapePercentEncoding}, "hello%2Cworld", "hello,world", false},
+		{"encoded newline", Options{Unescape: UnescapePercentEncoding}, "line%0Abreak", "line\nbreak", false},
+		{"plain", Options{Unescape: UnescapePercentEncoding}, "plain", "plain", false},
+		{"not unescaped by default", Options{}, "hello%2Cworld", "hello%2Cworld", false},
+		{"invalid escape", Options{Unescape: UnescapePercentEncoding}, "bad%zz", "", true},
+		{"invalid escape ignored", Options{Unescape: UnescapePercentEncoding, IgnoreFieldTypeErrors: true}, "bad%zz", "bad%zz", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			got, err := ProcessCSV[pair](&options, "A,B\n"+tt.cell+",b\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an unescape error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].A != tt.want {
+				t.Errorf("got %q, want %q", got[0].A, tt.want)
+			}
+		})
+	}
+}
+
+type trimmed struct {
+	Stars string `csv:"stars,trim=*"`
+	Mixed string `csv:"mixed,trim=*\" \t"`
+	Num   int    `csv:"num,trim=#<>"`
+	Plain string `csv:"plain"`
+}
+
+func TestTrimTagOption(t *testing.T) {
+	content := "stars,mixed,num,plain\n**a*b**,\"\"\"* \tx y\t *\"\"\",<#42#>,  keep  \n"
+	got, err := ProcessCSV[trimmed](&Options{UseStructTags: true}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := trimmed{Stars: "a*b", Mixed: "x y", Num: 42, Plain: "  keep  "}
+	if *got[0] != want {
+		t.Errorf("got %+v, want %+v", *got[0], want)
+	}
+}
+
+func TestStripCR(t *testing.T) {
+	content := "Level,Code,Comment\r\ninfo,1,\"done\r\"\r\nwarn,2,\"a\rb\r\"\r\n"
+	got, err := ProcessCSV[logLine](&Options{StripCR: true}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"done", "a\rb"} {
+		if got[i].Comment != want {
+			t.Errorf("row %d comment = %q, want %q", i, got[i].Comment, want)
+		}
+	}
+
+	var v logLine
+	if err := UnmarshalRecord(&Options{StripCR: true}, []string{"Level", "Code"}, []string{"info", "42\r"}, &v); err != nil || v.Code != 42 {
+		t.Errorf("got %+v, %v, want code 42", v, err)
+	}
+	if err := UnmarshalRecord(nil, []string{"Level", "Code"}, []string{"info", "42\r"}, &v); err == nil {
+		t.Error("expected a conversion error without StripCR")
+	}
+}
+
+type subscriber struct {
+	Name  string
+	Email string
+}
+
+func TestHeaderSynonyms(t *testing.T) {
+	options := func() *Options {
+		return &Options{HeaderSynonyms: map[string][]string{"Email": {"e-mail", "Email Address", "email"}}}
+	}
+	for _, header := range []string{"e-mail", "Email Address", "email", "Email"} {
+		t.Run(header, func(t *testing.T) {
+			got, err := ProcessCSV[subscriber](options(), "Name,"+header+"\nada,ada@example.com\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].Email != "ada@example.com" {
+				t.Errorf("got %+v, want the email bound", *got[0])
+			}
+		})
+	}
+	if _, err := ProcessCSV[subscriber](options(), "Name,mail\nada,x\n"); err == nil {
+		t.Error("expected an error for a header that is not a synonym")
+	}
+}
+
+type price struct {
+	Amount float64
+	Label  string
+}
+
+func TestFieldTransformMap(t *testing.T) {
+	options := &Options{FieldTransformMap: map[string]func(reflect.Value) error{
+		"Amount": func(v reflect.Value) error {
+			v.SetFloat(math.Round(v.Float()*100) / 100)
+			return nil
+		},
+		"Label": func(v reflect.Value) error {
+			if v.String() == "" {
+				return errors.New("empty label")
+			}
+			v.SetString(strings.ToUpper(v.String()))
+			return nil
+		},
+	}}
+	got, err := ProcessCSV[price](options, "Amount,Label\n3.14159,pi\n2.005,e\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Amount != 3.14 || got[0].Label != "PI" {
+		t.Errorf("got %+v, want {3.14 PI}", *got[0])
+	}
+	if got[1].Label != "E" {
+		t.Errorf("got %+v, want label E", *got[1])
+	}
+
+	if _, err := ProcessCSV[price](options, "Amount,Label\n1,\n"); err == nil {
+		t.Error("expected the transform error")
+	}
+	options.IgnoreFieldTypeErrors = true
+	if _, err := ProcessCSV[price](options, "Amount,Label\n1,\n"); err != nil {
+		t.Errorf("transform error not ignored with IgnoreFieldTypeErrors: %v", err)
+	}
+}
+
+func TestStrictHeaderMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wantErr string
+	}{
+		{"exact", "Level,Code,Comment", ""},
+		{"any order", "Comment,Level,Code", ""},
+		{"missing", "Level,Code", "missing [Comment]"},
+		{"extra", "Level,Code,Comment,Host", "extra [Host]"},
+		{"missing and extra", "Level,Host,Comment", "missing [Code], extra [Host]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &Options{StrictHeaderMatch: true, IgnoreUnknownFields: true}
+			row := strings.Repeat("1,", strings.Count(tt.header, ","))
+			_, err := ProcessCSV[logLine](options, tt.header+"\n"+row+"1\n")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type tagged struct {
+	Name  string `csv:"full_name"`
+	Email string `csv:"email"`
+}
+
+func TestResolveField(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   *Options
+		header    string
+		wantField string
+		wantOK    bool
+	}{
+		{"field name", nil, "Name", "Name", true},
+		{"field name unknown", nil, "full_name", "", false},
+		{"struct tag", &Options{UseStructTags: true}, "full_name", "Name", true},
+		{"struct tag ignores field name", &Options{UseStructTags: true}, "Name", "", false},
+		{"synonym", &Options{HeaderSynonyms: map[string][]string{"Email": {"e-mail"}}}, "e-mail", "Email", true},
+		{"schema", &Options{Schema: &Schema{Columns: []SchemaColumn{{Header: "contact", Field: "Email"}}}}, "contact", "Email", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, ok := ResolveField[tagged](tt.options, tt.header)
+			if field != tt.wantField || ok != tt.wantOK {
+				t.Errorf("got %q, %v, want %q, %v", field, ok, tt.wantField, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"struct tags", Options{UseStructTags: true}, "full_name,email\nada,ada@example.com\n", map[string]string{"full_name": "Name", "email": "Email"}, false},
+		{"header only", Options{UseStructTags: true}, "email,full_name", map[string]string{"full_name": "Name", "email": "Email"}, false},
+		{"synonyms", Options{HeaderSynonyms: map[string][]string{"Email": {"e-mail"}}}, "Name,e-mail\n", map[string]string{"Name": "Name", "e-mail": "Email"}, false},
+		{"unknown header", Options{}, "Name,Phone\n", nil, true},
+		{"unknown header ignored", Options{IgnoreUnknownFields: true}, "Name,Phone\n", map[string]string{"Name": "Name"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHeader[tagged](&tt.options, tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error for an unresolved header", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type hidden struct {
+	Name   string
+	secret string
+}
+
+type promotedInner struct {
+	Note string
+}
+
+type promoted struct {
+	promotedInner
+	Name string
+}
+
+func TestUnexportedField(t *testing.T) {
+	content := "Name,secret\nada,x\n"
+	_, err := ProcessCSV[hidden](nil, content)
+	if err == nil || !strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "unexported") {
+		t.Errorf("got error %v, want one naming the unexported field", err)
+	}
+
+	got, err := ProcessCSV[hidden](&Options{IgnoreUnknownFields: true}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got[0] != (hidden{Name: "ada"}) {
+		t.Errorf("got %+v, want the unexported field skipped", *got[0])
+	}
+
+	p, err := ProcessCSV[promoted](nil, "Name,Note\nada,hi\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p[0].Note != "hi" || p[0].Name != "ada" {
+		t.Errorf("got %+v, want the field promoted through an unexported embedded struct set", *p[0])
+	}
+}
+
+type ordered struct {
+	Index int    `csv:",rownum"`
+	Line  uint16 `csv:",rownum=1"`
+	Name  string `csv:"name"`
+}
+
+func TestRownumTagOption(t *testing.T) {
+	content := "name\na\nb\nc\nd\n"
+	tests := []struct {
+		name string
+		keep func(*ordered) bool
+		want []ordered
+	}{
+		{"all rows", func(*ordered) bool { return true }, []ordered{{0, 1, "a"}, {1, 2, "b"}, {2, 3, "c"}, {3, 4, "d"}}},
+		{"filtered rows", func(o *ordered) bool { return o.Name != "b" }, []ordered{{0, 1, "a"}, {2, 3, "c"}, {3, 4, "d"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSVFilter(&Options{UseStructTags: true}, content, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows []ordered
+			for _, o := range got {
+				rows = append(rows, *o)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("got %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+type splitDate struct {
+	Name  string
+	Date  time.Time `csv:"date,compose=year+month+day"`
+	Label string    `csv:"label,compose=day+month"`
+}
+
+func TestComposeTagOption(t *testing.T) {
+	funcs := map[string]ComposeFunc{
+		"Label": func(v *reflect.Value, values []string) error {
+			v.SetString(strings.Join(values, "/"))
+			return nil
+		},
+	}
+	tests := []struct {
+		name    string
+		options Options
+		content string
+		want    splitDate
+		wantErr bool
+	}{
+		{"three columns", Options{ComposeFuncMap: funcs}, "Name,year,month,day\nlaunch,2024,3,5\n", splitDate{"launch", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), "5/3"}, false},
+		{"reordered columns", Options{ComposeFuncMap: funcs}, "day,month,Name,year\n5,3,launch,2024\n", splitDate{"launch", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), "5/3"}, false},
+		{"empty day", Options{ComposeFuncMap: funcs}, "Name,year,month,day\nlaunch,2024,3,\n", splitDate{"launch", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "/3"}, false},
+		{"invalid date", Options{ComposeFuncMap: funcs}, "Name,year,month,day\nlaunch,2024,2,30\n", splitDate{}, true},
+		{"no compose function", Options{}, "Name,year,month,day\nlaunch,2024,3,5\n", splitDate{}, true},
+		{"missing source", Options{ComposeFuncMap: funcs}, "Name,year,month\nlaunch,2024,3\n", splitDate{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[splitDate](&tt.options, tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", *got[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g := *got[0]; g.Name != tt.want.Name || !g.Date.Equal(tt.want.Date) || g.Label != tt.want.Label {
+				t.Errorf("got %+v, want %+v", g, tt.want)
+			}
+		})
+	}
+
+	failing := map[string]ComposeFunc{"Label": func(*reflect.Value, []string) error { return fmt.Errorf("bad label") }}
+	if _, err := ProcessCSV[splitDate](&Options{ComposeFuncMap: failing}, "Name,year,month,day\nx,2024,3,5\n"); err == nil || !strings.Contains(err.Error(), "bad label") {
+		t.Errorf("got error %v, want the compose function's error", err)
+	}
+}
+
+func TestFieldBindingsConcurrentUse(t *testing.T) {
+	for _, options := range []*Options{{}, {UseFieldNames: true, UseStructTags: true}} {
+		shared := *options
+		mapping, err := NewFieldBindings[logLine](options, []string{"Level", "Code", "Comment"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 16)
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					var v logLine
+					if err := UnmarshalRecordMapped(options, mapping, []string{"info", strconv.Itoa(g*100 + i), "x"}, &v); err != nil {
+						errs <- err
+						return
+					}
+					if v.Code != g*100+i {
+						errs <- fmt.Errorf("got code %d, want %d", v.Code, g*100+i)
+						return
+					}
+					if _, err := ProcessCSV[logLine](options, "Level,Code,Comment\ninfo,1,x\n"); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(*options, shared) {
+			t.Errorf("options changed: UseFieldNames %v, UseStructTags %v, want %v, %v",
+				options.UseFieldNames, options.UseStructTags, shared.UseFieldNames, shared.UseStructTags)
+		}
+	}
+}
+
+func TestFieldTypeHints(t *testing.T) {
+	type row struct {
+		ID    any
+		Seen  any
+		Label any
+	}
+	options := &Options{FieldTypeHints: map[string]reflect.Type{
+		"ID":   reflect.TypeOf(int64(0)),
+		"Seen": reflect.TypeOf(time.Time{}),
+	}}
+	content := "ID,Seen,Label\n42,2024-01-02T03:04:05Z,7\n"
+
+	got, err := ProcessCSV[row](options, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := row{ID: int64(42), Seen: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Label: "7"}
+	if len(got) != 1 || !reflect.DeepEqual(*got[0], want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got, err = ProcessCSV[row](nil, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = row{ID: "42", Seen: "2024-01-02T03:04:05Z", Label: "7"}
+	if len(got) != 1 || !reflect.DeepEqual(*got[0], want) {
+		t.Errorf("got %+v without hints, want raw strings %+v", got, want)
+	}
+
+	if _, err := ProcessCSV[row](options, "ID\nx\n"); err == nil {
+		t.Error("expected an error for a value that does not parse as the hinted type")
+	}
+}
+
+func TestRejectControlChars(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"null byte", "A,B\nx\x00y,b\n", "field A contains disallowed character U+0000"},
+		{"byte order mark", "A,B\na,\uFEFFb\n", "field B contains disallowed character U+FEFF"},
+		{"escape", "A,B\na,\x1b[0m\n", "field B contains disallowed character U+001B"},
+		{"tab and carriage return", "A,B\na\tb,\"c\r\nd\"\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProcessCSV[pair](&Options{RejectControlChars: true}, tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+
+			if _, err := ProcessCSV[pair](nil, tt.content); err != nil {
+				t.Errorf("rejected without RejectControlChars: %v", err)
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package csv
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/spf13/cast"
 )
@@ -14,213 +19,1029 @@ import (
 // CustomMarshallingFunc is a function that can be used to customize the marshalling of a field.
 type CustomMarshallingFunc func(v *reflect.Value, fieldValue string) error
 
+// ComposeFunc sets a field tagged with the compose option from the values of its source columns, in the order the
+// tag names them.
+type ComposeFunc func(v *reflect.Value, values []string) error
+
+// UnknownTypeError is returned when a field's type is not built in, has no entry in CustomMarshallingFuncMap and has
+// no parser registered with RegisterParser.
+type UnknownTypeError struct {
+	Type  string // Type is the name of the field's type
+	Field string // Field is the header of the column being unmarshalled
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("no custom unmarshalling function found for type %s (field %s)", e.Type, e.Field)
+}
+
+// RowError is a row-level error collected under Options.CollectErrors.
+type RowError struct {
+	Row  int   // Row is the 1-based number of the data row, not counting the header
+	Line int   // Line is the input line the record starts on, when known, or 0
+	Err  error // Err is the error for the row
+}
+
+func (e *RowError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("row %d (line %d): %s", e.Row, e.Line, e.Err)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// RowErrors is returned, along with the records that parsed, when Options.CollectErrors collected row errors.
+type RowErrors []*RowError
+
+func (e RowErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d rows failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// UnescapeMode selects how cell content is unescaped before conversion.
+type UnescapeMode int
+
+const (
+	UnescapeNone            UnescapeMode = iota // UnescapeNone leaves cells as read
+	UnescapePercentEncoding                     // UnescapePercentEncoding decodes cells with url.QueryUnescape
+)
+
+// FloatSpecialPolicy determines how NaN and infinite values are handled for float fields.
+type FloatSpecialPolicy int
+
+const (
+	FloatSpecialAllow  FloatSpecialPolicy = iota // FloatSpecialAllow keeps the IEEE value
+	FloatSpecialReject                           // FloatSpecialReject fails the conversion
+	FloatSpecialZero                             // FloatSpecialZero substitutes 0
+)
+
+// EmptyNumericPolicy determines how empty cells are handled for numeric fields.
+type EmptyNumericPolicy int
+
+const (
+	EmptyNumericError EmptyNumericPolicy = iota // EmptyNumericError fails the conversion, subject to IgnoreFieldTypeErrors
+	EmptyNumericZero                            // EmptyNumericZero sets the field to zero
+	EmptyNumericSkip                            // EmptyNumericSkip leaves the field's prior value untouched
+)
+
 // Options defines general configuration of CSV processing.
 type Options struct {
-	Separator        rune // Separator character (defaults to ',')
-	LazyQuotes       bool // LazyQuotes is a flag that determines whether quotes should be escaped if they are part of the data (defaults to false)
-	FieldsPerRecord  int  // FieldsPerRecord is the number of expected fields per record (defaults to -1, meaning any number of fields)
-	TrimLeadingSpace bool // TrimLeadingSpace is a flag that determines whether leading white space in a field is trimmed (defaults to false)
-	Comment          rune // Comment character (defaults to '#')
-
-	IgnoreUnknownFields      bool // IgnoreUnknownFields is a flag that determines whether to ignore fields that are not defined in the struct (defaults to false)
-	IgnoreFieldTypeErrors    bool // IgnoreFieldTypeErrors is a flag that determines whether to ignore field type errors (defaults to false)
-	UseFieldNames            bool // UseFieldNames is a flag that indicates to use struct field names
-	UseStructTags            bool // UseStructTags is a flag that indicates to use struct field tags
+	Separator           rune   // Separator character (defaults to ',')
+	AutoDetectSeparator bool   // AutoDetectSeparator is a flag that detects the separator from the first lines of input, falling back to Separator (defaults to false)
+	DetectCandidates    []rune // DetectCandidates are the separators considered by AutoDetectSeparator (defaults to comma, semicolon and tab)
+	LazyQuotes          bool   // LazyQuotes is a flag that determines whether quotes should be escaped if they are part of the data (defaults to false)
+	MaxLineBytes        int    // MaxLineBytes is the largest size in bytes a record may reach when not 0, guarding against an unterminated quote swallowing the rest of the input
+	FieldsPerRecord     int    // FieldsPerRecord is the number of expected fields per record (defaults to -1, meaning any number of fields)
+	MinFields           int    // MinFields is the fewest fields a record may have when not 0, replacing FieldsPerRecord; fields missing from shorter records are left zero
+	MaxFields           int    // MaxFields is the most fields a record may have when not 0; fields past the header are dropped unless JoinTrailingInto collects them
+	TrimLeadingSpace    bool   // TrimLeadingSpace is a flag that determines whether leading white space in a field is trimmed (defaults to false)
+	Comment             rune   // Comment character (defaults to '#')
+	CommentPrefix       string // CommentPrefix drops lines beginning with it (e.g. "--" or "//") before parsing, including lines inside quoted fields; it may be combined with Comment
+	CommentHeaderOnly   bool   // CommentHeaderOnly is a flag that determines whether Comment only applies to lines before the first record, so later data may begin with it (defaults to false)
+	RecordSeparator     rune   // RecordSeparator is the character separating records when it is not a newline (e.g. 0x1E); a separator inside quotes still ends the record (defaults to '\n')
+	EnforceHeaderWidth  bool   // EnforceHeaderWidth is a flag that rejects rows with more fields than the header, replacing FieldsPerRecord checks; narrower rows are accepted (defaults to false)
+	RepairQuotes        bool   // RepairQuotes is a flag that determines whether records with unbalanced or stray quotes are repaired line by line instead of failing to parse (defaults to false)
+	StringSeparator     string // StringSeparator is a field separator of one or more characters (e.g. "||") that takes precedence over Separator; fields may be quoted, but LazyQuotes and TrimLeadingSpace do not apply when it is longer than one character
+	EscapeChar          rune   // EscapeChar, when set, makes the character escape a following separator, newline or itself instead of standard quote processing, which is disabled in this mode (e.g. '\\' for MySQL exports)
+	WhitespaceDelimited bool   // WhitespaceDelimited is a flag that splits each line on runs of white space instead of Separator; quoting is not supported in this mode (defaults to false)
+
+	IgnoreUnknownFields      bool                                 // IgnoreUnknownFields is a flag that determines whether to ignore fields that are not defined in the struct (defaults to false)
+	IgnoreFieldTypeErrors    bool                                 // IgnoreFieldTypeErrors is a flag that determines whether to ignore field type errors (defaults to false)
+	CollectErrors            bool                                 // CollectErrors is a flag that determines whether row errors, such as a wrong number of fields or a failed conversion, are collected into a RowErrors returned with the rows that parsed instead of aborting (defaults to false)
+	UseFieldNames            bool                                 // UseFieldNames is a flag that indicates to use struct field names
+	UseStructTags            bool                                 // UseStructTags is a flag that indicates to use struct field tags
+	StrictHeaderMatch        bool                                 // StrictHeaderMatch is a flag that requires the headers to bind to exactly the struct's bindable fields, in any order (defaults to false)
+	JoinTrailingInto         string                               // JoinTrailingInto names the struct field bound to the last column that receives any fields past the header joined by Separator (defaults to "")
+	HeaderOverride           []string                             // HeaderOverride supplies the header names explicitly; when set no line is consumed as a header
+	HeaderRows               int                                  // HeaderRows is the number of records forming the header, merged column by column, e.g. a row of names and a row of units (defaults to 1)
+	HeaderJoin               *string                              // HeaderJoin joins the cells of a column's header rows when HeaderRows is more than one, and may point to "" to join them directly; empty cells are skipped (defaults to " " when nil)
+	KeyValueMode             bool                                 // KeyValueMode is a flag that reads each field as key=value, binding the value to the field the key resolves to; no header row is read (defaults to false)
+	Schema                   *Schema                              // Schema maps headers to struct fields and type hints ahead of the other resolution options
+	HeaderSynonyms           map[string][]string                  // HeaderSynonyms maps a struct field name to header variants that also bind to it (e.g. "Email": {"e-mail", "Email Address"})
+	InferAndEnforceTypes     bool                                 // InferAndEnforceTypes is a flag that infers each column's kind (bool, int, float, time or string) from its first non-empty cell and rejects later rows that drift from it; int columns may widen to float (defaults to false)
+	Deduplicate              bool                                 // Deduplicate is a flag that determines whether records repeating an earlier record are skipped, keeping the first occurrence (defaults to false)
+	DedupeKeyFields          []string                             // DedupeKeyFields are the headers of the columns compared by Deduplicate; when empty the whole record is compared
+	RowHashFunc              func(record []string) string         // RowHashFunc computes the row hashes returned by ProcessCSVWithHashes (defaults to FNVRowHash)
+	RejectControlChars       bool                                 // RejectControlChars is a flag that determines whether cells containing null bytes, a BOM or other control characters (except tab, carriage return and newline) are rejected (defaults to false)
+	Unescape                 UnescapeMode                         // Unescape selects how cells are unescaped before conversion (defaults to UnescapeNone)
+	FloatSpecialPolicy       FloatSpecialPolicy                   // FloatSpecialPolicy determines how NaN and infinite float values are handled (defaults to FloatSpecialAllow)
+	NullValues               []string                             // NullValues are cell values, matched exactly, read as empty so that pointer fields stay nil (e.g. \N for Postgres COPY)
+	QuotedEmptyAsValue       bool                                 // QuotedEmptyAsValue is a flag that determines whether a quoted empty field ("") sets a pointer field to a pointer to the zero value; bare empty fields always leave pointer fields nil. Only the standard CSV reader tracks quoting, and with LazyQuotes a field beginning with a stray quote counts as quoted (defaults to false)
+	StripCR                  bool                                 // StripCR is a flag that determines whether a trailing carriage return is trimmed from each cell before conversion (defaults to false)
+	BoolTrueValues           []string                             // BoolTrueValues are extra tokens, matched exactly, that parse as true for bool fields (e.g. "yes", "✓")
+	BoolFalseValues          []string                             // BoolFalseValues are extra tokens, matched exactly, that parse as false for bool fields (e.g. "no", "✗")
+	CheckboxBool             bool                                 // CheckboxBool is a flag that parses bool fields as true when the cell is non-empty and false when it is empty (defaults to false)
+	LenientBool              bool                                 // LenientBool is a flag that determines whether bool fields accept numeric values such as 1.0 and 0.0 (defaults to false)
+	StripOuterQuotes         bool                                 // StripOuterQuotes is a flag that determines whether matching outer quotes left in numeric cells (e.g. by LazyQuotes) are removed before conversion (defaults to false)
+	Locale                   string                               // Locale is a BCP 47 tag (e.g. "de-DE") whose grouping and decimal separators are used to parse numeric fields; when empty numbers are parsed with cast
+	CurrencyStrip            bool                                 // CurrencyStrip is a flag that determines whether currency symbols and space around numeric cells (e.g. "$1,234.56", "1.234,56 €") are removed before conversion (defaults to false)
+	CurrencySymbols          []string                             // CurrencySymbols are the symbols CurrencyStrip removes (e.g. "USD", "$"); when empty any Unicode currency symbol is removed
+	AccountingNegatives      bool                                 // AccountingNegatives is a flag that determines whether numeric cells wrapped in parentheses (e.g. "(1,234)") are read as negative and, without a Locale, whether "," grouping separators are removed (defaults to false)
+	SliceDelimiter           string                               // SliceDelimiter separates the elements of slice fields and the entries of map fields within a cell; elements may not contain it (defaults to ";")
+	MapKeySeparator          string                               // MapKeySeparator separates the key from the element in each entry of a map field; keys may not contain it (defaults to "=")
+	TimeLayouts              []string                             // TimeLayouts are the layouts tried in order for time.Time fields; the first that parses wins; preset names such as RFC1123 or DateOnly are accepted (defaults to RFC3339)
+	AutoDetectTimeFormat     bool                                 // AutoDetectTimeFormat is a flag that, when TimeLayouts is empty, parses time.Time cells with the first match of RFC3339, DateTime, DateOnly and RFC1123, or as Unix seconds when the cell is an integer (defaults to false)
+	TimeLocation             *time.Location                       // TimeLocation is the location of time.Time values whose layout has no zone (defaults to UTC)
+	DurationFormat           DurationFormat                       // DurationFormat selects how time.Duration fields are parsed (defaults to DurationFormatGo)
+	DurationUnit             DurationUnit                         // DurationUnit selects how MarshalCSV writes time.Duration fields; integer units are also accepted when parsing (defaults to DurationUnitString)
+	EmptyNumericPolicy       EmptyNumericPolicy                   // EmptyNumericPolicy determines how empty cells are handled for numeric fields (defaults to EmptyNumericError)
+	WarnOnLeadingZeroLoss    bool                                 // WarnOnLeadingZeroLoss is a flag that counts integer cells with significant leading zeros, such as zip code 07030, in Stats.LeadingZeroLosses (defaults to false)
+	StrictLeadingZeros       bool                                 // StrictLeadingZeros is a flag that makes integer cells with significant leading zeros a conversion error instead of dropping the zeros (defaults to false)
+	EnumMap                  map[string]map[string]int64          // EnumMap maps a named integer type (e.g. "main.Status") to its labels and their values
+	FieldTransformMap        map[string]func(reflect.Value) error // FieldTransformMap maps a struct field name to a function run on the field after it is set, e.g. to round or canonicalize it
+	FieldTypeHints           map[string]reflect.Type              // FieldTypeHints maps a header to the type parsed into an empty interface field; without a hint the raw string is stored
+	Validate                 bool                                 // Validate is a flag that determines whether each record is passed to Validator after it is unmarshalled (defaults to false)
+	Validator                func(v interface{}) error            // Validator validates an unmarshalled record; see the validate subpackage for go-playground/validator support
+	StopFunc                 func(headers, record []string) bool  // StopFunc is consulted before each record is unmarshalled; returning true ends parsing and returns the records collected so far
+	Stats                    *ProcessStats                        // Stats, when non-nil, is populated with counts of parsed rows, skipped fields and coerced values
+	ComposeFuncMap           map[string]ComposeFunc               // ComposeFuncMap maps the name of a field tagged with the compose option to the function building it; time.Time fields default to composing year, month, day, hour, minute and second columns
+	SkipUnsupportedTypes     bool                                 // SkipUnsupportedTypes is a flag that determines whether fields with no built-in or custom conversion are left zero instead of returning an UnknownTypeError (defaults to false)
 	CustomMarshallingFuncMap map[string]CustomMarshallingFunc
+
+	MarshalFields   []string // MarshalFields are the headers MarshalCSV writes, in order; when empty every field is written
+	WriteNullAs     string   // WriteNullAs is written by MarshalCSV for empty strings and nil pointers (e.g. \N for Postgres COPY; defaults to "")
+	TrailingNewline *bool    // TrailingNewline determines whether MarshalCSV ends the last record with a newline (defaults to true when nil)
+	FloatFormat     byte     // FloatFormat is the strconv.FormatFloat format ('f', 'e' or 'g') MarshalCSV uses for floats (defaults to 'g')
+	ZeroAsEmpty     bool     // ZeroAsEmpty is a flag that determines whether MarshalCSV writes empty cells for non-pointer fields holding their zero value; pointer fields are written whenever they are non-nil (defaults to false)
+	WriteBOM        bool     // WriteBOM is a flag that determines whether output begins with a UTF-8 byte order mark so that Excel detects the encoding (defaults to false)
+	FlushEvery      int      // FlushEvery is the number of records after which WriteCSV flushes its output, as it does when done; 0 flushes only when done
+	HeaderOnEmpty   bool     // HeaderOnEmpty is a flag that determines whether WriteCSVChan writes the header when the channel closes without a value (defaults to false)
+}
+
+// DefaultOptions returns Options with the defaults documented on each field set explicitly. It is the recommended
+// starting point for configuring a parse.
+func DefaultOptions() *Options {
+	return &Options{
+		Separator:                ',',
+		Comment:                  '#',
+		FieldsPerRecord:          -1,
+		UseFieldNames:            true,
+		CustomMarshallingFuncMap: map[string]CustomMarshallingFunc{},
+	}
 }
 
-// ProcessCSV processes CSV input and returns a slice of structs.
+// ProcessCSV processes CSV input and returns a slice of structs. Empty input returns a nil slice, while input with
+// a header but no data rows returns an empty, non-nil slice. Pointer fields, such as *int or *time.Time, are left nil
+// for empty cells and otherwise point to the value parsed as for the element type.
 func ProcessCSV[T any](options *Options, content string) ([]*T, error) {
-	r := csv.NewReader(strings.NewReader(content))
+	return processReader[T](options, strings.NewReader(content), processHooks[T]{})
+}
+
+// ProcessCSVFilter processes CSV input and returns a slice of the structs for which keep returns true. keep is called
+// after each record is fully unmarshalled.
+func ProcessCSVFilter[T any](options *Options, content string, keep func(*T) bool) ([]*T, error) {
+	return processReader(options, strings.NewReader(content), processHooks[T]{keep: keep})
+}
 
-	if options != nil {
-		if options.Separator != 0 {
-			r.Comma = options.Separator
+// ProcessCSVOnRecord processes CSV input like ProcessCSV, calling onRecord with the index and value of each struct as
+// soon as it is unmarshalled and validated, before the full slice is returned.
+func ProcessCSVOnRecord[T any](options *Options, content string, onRecord func(index int, v *T)) ([]*T, error) {
+	return processReader(options, strings.NewReader(content), processHooks[T]{onRecord: onRecord})
+}
+
+// ProcessCSVMulti processes the concatenation of readers as one CSV input whose header is read from the first reader
+// only. Each reader must hold whole records; a reader not ending in a newline is terminated with one so that its last
+// record is not joined to the first record of the next.
+func ProcessCSVMulti[T any](options *Options, readers ...io.Reader) ([]*T, error) {
+	parts := make([]io.Reader, len(readers))
+	for i, r := range readers {
+		parts[i] = &terminatedReader{r: r}
+	}
+	return processReader[T](options, io.MultiReader(parts...), processHooks[T]{})
+}
+
+// processHooks are optional callbacks invoked by processReader.
+type processHooks[T any] struct {
+	keep     func(*T) bool                  // keep drops structs for which it returns false
+	record   func(headers, record []string) // record observes each raw record before it is unmarshalled
+	onRecord func(index int, v *T)          // onRecord observes each struct as it is appended to the result
+	onError  func(err *RowError)            // onError receives each row error as it occurs; the row is skipped as with CollectErrors
+	discard  bool                           // discard drops structs once onRecord has observed them instead of returning them
+}
+
+// processReader reads a header and records from rd with the reader selected by options and unmarshals them.
+func processReader[T any](options *Options, rd io.Reader, hooks processHooks[T]) ([]*T, error) {
+	options = normalizeOptions(options)
+	return processRecords(options, newRecordReader(options, rd), hooks)
+}
+
+// processRecords reads a header and records from r and unmarshals them. options must already be normalized.
+func processRecords[T any](options *Options, r recordReader, hooks processHooks[T]) ([]*T, error) {
+	if options.KeyValueMode {
+		return processKeyValueRecords(options, r, hooks)
+	}
+
+	headers, err := readHeader(options, r)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv: %w", err)
+	}
+
+	var dedupe *deduper
+	if options.Deduplicate {
+		dedupe, err = newDeduper(options, headers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sink := newRecordSink(options, hooks)
+	sink.ts = []*T{}
+	var mapping []FieldBinding
+
+	var enforcer *typeEnforcer
+	if options.InferAndEnforceTypes {
+		enforcer = &typeEnforcer{}
+	}
+
+	if options.StrictHeaderMatch || options.JoinTrailingInto != "" {
+		mapping, err = NewFieldBindings[T](options, headers)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling record: %w", err)
+		}
+	}
+	if options.StrictHeaderMatch {
+		if err := checkHeaderMatch(options, reflect.TypeOf((*T)(nil)).Elem(), mapping); err != nil {
+			return nil, err
+		}
+	}
+	if options.JoinTrailingInto != "" && (len(mapping) == 0 || mapping[len(mapping)-1].Name != options.JoinTrailingInto) {
+		return nil, fmt.Errorf("JoinTrailingInto field %s is not bound to the last column", options.JoinTrailingInto)
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
 		}
-		if options.LazyQuotes {
-			r.LazyQuotes = true
+		sink.row++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if errors.Is(err, csv.ErrFieldCount) {
+					want := options.FieldsPerRecord
+					if want <= 0 {
+						want = len(headers)
+					}
+					err = fmt.Errorf("record has %d fields, want %d: %w", len(record), want, err)
+				}
+				if sink.collect(parseErr.StartLine, err) {
+					continue
+				}
+			}
+			return nil, fmt.Errorf("error reading csv: %w", err)
 		}
-		if options.FieldsPerRecord != 0 {
-			r.FieldsPerRecord = options.FieldsPerRecord
+		if n := len(record); (options.MinFields > 0 && n < options.MinFields) || (options.MaxFields > 0 && n > options.MaxFields) {
+			err := fmt.Errorf("record has %d fields, want %s", n, fieldRange(options))
+			if sink.collect(0, err) {
+				continue
+			}
+			return nil, fmt.Errorf("error reading csv: row %d: %w", sink.row, err)
 		}
-		if options.TrimLeadingSpace {
-			r.TrimLeadingSpace = true
+		if options.JoinTrailingInto != "" && len(record) > len(headers) {
+			record = joinTrailing(options, record, len(headers))
 		}
-		if options.Comment != 0 {
-			r.Comment = options.Comment
+		if options.MaxFields > 0 && len(record) > len(headers) {
+			record = record[:len(headers)]
+		}
+		if options.EnforceHeaderWidth && len(record) > len(headers) {
+			if sink.collect(0, fmt.Errorf("record has %d fields but the header has %d", len(record), len(headers))) {
+				continue
+			}
+			return nil, fmt.Errorf("error reading csv: row %d has %d fields but the header has %d", sink.row, len(record), len(headers))
+		}
+
+		if options.StopFunc != nil && options.StopFunc(headers, record) {
+			break
+		}
+
+		if enforcer != nil {
+			if err := enforcer.check(headers, record); err != nil {
+				if sink.collect(0, fmt.Errorf("type drift: %w", err)) {
+					continue
+				}
+				return nil, fmt.Errorf("row %d: type drift: %w", sink.row, err)
+			}
 		}
 
-		if !options.UseFieldNames && !options.UseStructTags {
-			options.UseFieldNames = true
+		if hooks.record != nil {
+			hooks.record(headers, record)
 		}
 
-		if options.UseFieldNames && options.UseStructTags {
-			options.UseStructTags = false
+		if dedupe != nil && dedupe.duplicate(record) {
+			continue
+		}
+
+		if mapping == nil {
+			mapping, err = NewFieldBindings[T](options, headers)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshalling record: %w", err)
+			}
+		}
+
+		if err := sink.add(mapping, record, quotedFields(r)); err != nil {
+			return nil, err
 		}
 	}
+	return sink.result()
+}
 
-	headers, err := r.Read()
+// recordSink unmarshals the records of a parse and gathers the structs and row errors, applying Validate,
+// CollectErrors and the hooks. It is shared by processRecords and processKeyValueRecords.
+type recordSink[T any] struct {
+	options *Options
+	hooks   processHooks[T]
+	rownums []rownumField
+	ts      []*T
+	rowErrs RowErrors
+	row     int // row is the 1-based number of the record being processed
+	kept    int // kept is the number of structs passed to the hooks so far
+}
+
+func newRecordSink[T any](options *Options, hooks processHooks[T]) *recordSink[T] {
+	return &recordSink[T]{options: options, hooks: hooks, rownums: rownumFields(reflect.TypeOf((*T)(nil)).Elem())}
+}
+
+// collect passes err for the current row to hooks.onError or, with CollectErrors, gathers it so that the row is
+// skipped. It reports false when the error should end parsing instead.
+func (s *recordSink[T]) collect(line int, err error) bool {
+	if s.hooks.onError != nil {
+		s.hooks.onError(&RowError{Row: s.row, Line: line, Err: err})
+		return true
+	}
+	if !s.options.CollectErrors {
+		return false
+	}
+	s.rowErrs = append(s.rowErrs, &RowError{Row: s.row, Line: line, Err: err})
+	return true
+}
+
+// add unmarshals record into a new struct, validates it and passes it to the hooks. Row errors are collected when
+// possible; an error is returned only when parsing should end.
+func (s *recordSink[T]) add(mapping []FieldBinding, record []string, quoted []bool) error {
+	t := new(T)
+	setRownums(s.rownums, reflect.ValueOf(t).Elem(), s.row-1)
+	if err := unmarshalMapped(s.options, mapping, record, quoted, reflect.ValueOf(t).Elem()); err != nil {
+		if s.collect(0, err) {
+			return nil
+		}
+		return fmt.Errorf("error unmarshalling record: %w", err)
+	}
+	if s.options.Validate {
+		if s.options.Validator == nil {
+			return fmt.Errorf("validation enabled but no Validator set")
+		}
+		if err := s.options.Validator(t); err != nil {
+			if s.collect(0, fmt.Errorf("validation failed: %w", err)) {
+				return nil
+			}
+			return fmt.Errorf("row %d: validation failed: %w", s.row, err)
+		}
+	}
+	if s.hooks.keep != nil && !s.hooks.keep(t) {
+		return nil
+	}
+	if !s.hooks.discard {
+		s.ts = append(s.ts, t)
+	}
+	if s.hooks.onRecord != nil {
+		s.hooks.onRecord(s.kept, t)
+	}
+	s.kept++
+	return nil
+}
+
+// result returns the structs gathered and, when any row errors were collected, a RowErrors.
+func (s *recordSink[T]) result() ([]*T, error) {
+	if len(s.rowErrs) > 0 {
+		return s.ts, s.rowErrs
+	}
+	return s.ts, nil
+}
+
+// UnmarshalLine parses a single line of CSV and unmarshals it into a struct, binding the fields of the line to the
+// struct's exported fields in declaration order. No header is read.
+func UnmarshalLine[T any](options *Options, line string, v *T) error {
+	options = normalizeOptions(options)
+	rt := reflect.TypeOf(v).Elem()
+	if rt.Kind() != reflect.Struct {
+		return fmt.Errorf("expected struct, got %s", rt.Kind())
+	}
+
+	record, err := newRecordReader(options, strings.NewReader(line)).Read()
 	if err == io.EOF {
-		return nil, nil
+		return fmt.Errorf("error reading csv: empty line")
+	}
+	if err != nil {
+		return fmt.Errorf("error reading csv: %w", err)
 	}
 
-	ts := []*T{}
+	headers, _ := marshalColumns(rt, "", options.UseStructTags)
+	return UnmarshalRecord(options, headers, record, v)
+}
 
-	for {
-		record, err := r.Read()
+// readHeader returns Options.HeaderOverride when set and otherwise reads the header from r, merging HeaderRows
+// records when it is more than one.
+func readHeader(options *Options, r recordReader) ([]string, error) {
+	if len(options.HeaderOverride) > 0 {
+		return options.HeaderOverride, nil
+	}
+	headers, err := r.Read()
+	for i := 1; err == nil && i < options.HeaderRows; i++ {
+		var more []string
+		more, err = r.Read()
 		if err == io.EOF {
-			break
+			err = fmt.Errorf("header has %d of %d rows", i, options.HeaderRows)
 		}
-		if err != nil {
-			return nil, fmt.Errorf("error reading csv: %s", err)
+		if err == nil {
+			headers = mergeHeaders(options, headers, more)
+		}
+	}
+	return headers, err
+}
+
+// mergeHeaders joins the cells of a further header row onto headers column by column with Options.HeaderJoin. Empty
+// cells add nothing, and a row wider than headers adds columns.
+func mergeHeaders(options *Options, headers, row []string) []string {
+	join := " "
+	if options.HeaderJoin != nil {
+		join = *options.HeaderJoin
+	}
+	for i, cell := range row {
+		switch {
+		case i >= len(headers):
+			headers = append(headers, cell)
+		case cell == "":
+		case headers[i] == "":
+			headers[i] = cell
+		default:
+			headers[i] += join + cell
 		}
+	}
+	return headers
+}
+
+// fieldRange describes the range of field counts accepted by MinFields and MaxFields.
+func fieldRange(options *Options) string {
+	switch {
+	case options.MaxFields <= 0:
+		return fmt.Sprintf("at least %d", options.MinFields)
+	case options.MinFields <= 0:
+		return fmt.Sprintf("at most %d", options.MaxFields)
+	default:
+		return fmt.Sprintf("%d to %d", options.MinFields, options.MaxFields)
+	}
+}
 
-		t := new(T)
+// joinTrailing joins the fields of record from the last header column on with the field separator, so that a record
+// wider than the header of width n fits it.
+func joinTrailing(options *Options, record []string, n int) []string {
+	joined := strings.Join(record[n-1:], fieldSeparator(options))
+	return append(record[:n-1:n-1], joined)
+}
 
-		ts = append(ts, t)
-		err = UnmarshalRecord(options, headers, record, ts[len(ts)-1])
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshalling record: %s", err)
+// fieldSeparator returns the separator between fields: StringSeparator when set, otherwise Separator or ",".
+func fieldSeparator(options *Options) string {
+	if options.StringSeparator != "" {
+		return options.StringSeparator
+	}
+	if options.Separator != 0 {
+		return string(options.Separator)
+	}
+	return ","
+}
+
+// normalizeOptions returns options with the field resolution mode settled, allocating defaults when options is nil.
+// options is never modified; a copy is returned when it needs settling, so one Options may be shared by concurrent
+// calls.
+func normalizeOptions(options *Options) *Options {
+	if options == nil {
+		return &Options{UseFieldNames: true}
+	}
+	if options.UseFieldNames != options.UseStructTags {
+		return options
+	}
+
+	normalized := *options
+	normalized.UseFieldNames = true
+	normalized.UseStructTags = false
+	return &normalized
+}
+
+// recordOptions are the options that act across records or on the header. Entry points that do not read records
+// through processRecords support only some of them and reject the rest with unsupportedOption.
+var recordOptions = []struct {
+	name string
+	set  func(options *Options) bool
+}{
+	{"Deduplicate", func(o *Options) bool { return o.Deduplicate }},
+	{"StopFunc", func(o *Options) bool { return o.StopFunc != nil }},
+	{"Validate", func(o *Options) bool { return o.Validate }},
+	{"InferAndEnforceTypes", func(o *Options) bool { return o.InferAndEnforceTypes }},
+	{"MinFields", func(o *Options) bool { return o.MinFields > 0 }},
+	{"MaxFields", func(o *Options) bool { return o.MaxFields > 0 }},
+	{"CollectErrors", func(o *Options) bool { return o.CollectErrors }},
+	{"EnforceHeaderWidth", func(o *Options) bool { return o.EnforceHeaderWidth }},
+	{"JoinTrailingInto", func(o *Options) bool { return o.JoinTrailingInto != "" }},
+	{"StrictHeaderMatch", func(o *Options) bool { return o.StrictHeaderMatch }},
+	{"KeyValueMode", func(o *Options) bool { return o.KeyValueMode }},
+	{"HeaderRows", func(o *Options) bool { return o.HeaderRows > 1 }},
+	{"HeaderOverride", func(o *Options) bool { return len(o.HeaderOverride) > 0 }},
+	{"CommentHeaderOnly", func(o *Options) bool { return o.CommentHeaderOnly }},
+}
+
+// unsupportedOption returns the name of the first of recordOptions set in options that is not named in supported.
+func unsupportedOption(options *Options, supported ...string) (string, bool) {
+	for _, option := range recordOptions {
+		if !option.set(options) {
+			continue
+		}
+		ok := false
+		for _, name := range supported {
+			if name == option.name {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return option.name, true
 		}
 	}
+	return "", false
+}
+
+// ProcessStats holds data-quality counters populated while processing when Options.Stats is set. The counters are
+// added to, not reset, so they accumulate across calls sharing the same ProcessStats; reset it with
+// *stats = ProcessStats{} to count one parse at a time. It is not safe for concurrent use by multiple parses.
+type ProcessStats struct {
+	Rows              int // Rows is the number of records unmarshalled
+	SkippedFields     int // SkippedFields is the number of cells not bound because IgnoreUnknownFields ignored their column
+	CoercedValues     int // CoercedValues is the number of cells whose conversion error was ignored because of IgnoreFieldTypeErrors
+	RepairedRows      int // RepairedRows is the number of records whose quoting was repaired because of RepairQuotes
+	LeadingZeroLosses int // LeadingZeroLosses is the number of integer cells whose leading zeros were dropped, counted when WarnOnLeadingZeroLoss is set
+}
 
-	return ts, nil
+// ignoreTypeError reports whether a field type error should be ignored, counting it in Stats when it is.
+func (o *Options) ignoreTypeError() bool {
+	if !o.IgnoreFieldTypeErrors {
+		return false
+	}
+	if o.Stats != nil {
+		o.Stats.CoercedValues++
+	}
+	return true
 }
 
 // UnmarshalRecord unmarshals a single record into a struct.
 func UnmarshalRecord[T any](options *Options, headers []string, record []string, v *T) error {
-	s := reflect.ValueOf(v).Elem()
-	for i := 0; i < len(record); i++ {
-		var fieldName string
-		if options.UseFieldNames {
-			fieldName = headers[i]
-		}
-		if options.UseStructTags {
-			var err error
-			fieldName, err = getFieldNameFromStructTag(headers[i], "csv", v)
-			if err != nil {
-				return fmt.Errorf("error getting field name from struct tag: %s", err)
+	if len(record) > len(headers) {
+		return fmt.Errorf("record has %d fields but only %d headers", len(record), len(headers))
+	}
+	mapping, err := NewFieldBindings[T](options, headers[:len(record)])
+	if err != nil {
+		return err
+	}
+	return UnmarshalRecordMapped(options, mapping, record, v)
+}
+
+// unmarshalSlice splits value on Options.SliceDelimiter and unmarshals each element. An empty value leaves a nil slice.
+func unmarshalSlice(options *Options, f reflect.Value, header, value string) error {
+	if value == "" {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+
+	parts := strings.Split(value, sliceDelimiter(options))
+	sv := reflect.MakeSlice(f.Type(), len(parts), len(parts))
+	for j, part := range parts {
+		if err := unmarshalField(options, sv.Index(j), header, part); err != nil {
+			return err
+		}
+	}
+	f.Set(sv)
+	return nil
+}
+
+// unmarshalMap splits value into entries on Options.SliceDelimiter and each entry into a key and element on
+// Options.MapKeySeparator, unmarshalling both. An empty value leaves the map nil.
+func unmarshalMap(options *Options, f reflect.Value, header, value string) error {
+	if value == "" {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+
+	m := reflect.MakeMap(f.Type())
+	for _, entry := range strings.Split(value, sliceDelimiter(options)) {
+		k, v, ok := strings.Cut(entry, mapKeySeparator(options))
+		if !ok {
+			if !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed: map entry %q has no key separator", header, entry)
 			}
+			continue
+		}
+		kv := reflect.New(f.Type().Key()).Elem()
+		if err := unmarshalField(options, kv, header, k); err != nil {
+			return err
 		}
+		ev := reflect.New(f.Type().Elem()).Elem()
+		if err := unmarshalField(options, ev, header, v); err != nil {
+			return err
+		}
+		m.SetMapIndex(kv, ev)
+	}
+	f.Set(m)
+	return nil
+}
 
-		if fieldName == "" {
-			return fmt.Errorf("unknown field: %s", headers[i])
+// unmarshalArray splits value on Options.SliceDelimiter and unmarshals each element. The number of elements must
+// match the array length; when the error is ignored the elements that fit are assigned.
+func unmarshalArray(options *Options, f reflect.Value, header, value string) error {
+	var parts []string
+	if value != "" {
+		parts = strings.Split(value, sliceDelimiter(options))
+	}
+	if len(parts) != f.Len() {
+		if !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: got %d elements, want %d", header, len(parts), f.Len())
+		}
+		if len(parts) > f.Len() {
+			parts = parts[:f.Len()]
 		}
+	}
 
-		f := s.FieldByName(fieldName)
-		if !options.IgnoreUnknownFields && !f.IsValid() {
-			return fmt.Errorf("unknown field: %s", headers[i])
+	for j, part := range parts {
+		if err := unmarshalField(options, f.Index(j), header, part); err != nil {
+			return err
 		}
-		if options.IgnoreUnknownFields && !f.IsValid() {
-			continue
+	}
+	return nil
+}
+
+func sliceDelimiter(options *Options) string {
+	if options.SliceDelimiter == "" {
+		return ";"
+	}
+	return options.SliceDelimiter
+}
+
+// mapKeySeparator returns Options.MapKeySeparator, defaulting to "=".
+func mapKeySeparator(options *Options) string {
+	if options.MapKeySeparator == "" {
+		return "="
+	}
+	return options.MapKeySeparator
+}
+
+// unmarshalField converts a single cell value and assigns it to the field.
+func unmarshalField(options *Options, f reflect.Value, header, value string) error {
+	if f.Kind() == reflect.Slice && f.Type().Elem().Kind() != reflect.Uint8 {
+		return unmarshalSlice(options, f, header, value)
+	}
+	if f.Kind() == reflect.Array && f.Type().Elem().Kind() != reflect.Uint8 {
+		return unmarshalArray(options, f, header, value)
+	}
+	if f.Kind() == reflect.Map {
+		return unmarshalMap(options, f, header, value)
+	}
+
+	if options.StripOuterQuotes && isNumericKind(f.Kind()) {
+		value = stripOuterQuotes(value)
+	}
+	if options.AccountingNegatives && isNumericKind(f.Kind()) && f.Type().String() != "time.Duration" {
+		value = accountingNumber(value, options.Locale == "")
+	}
+	if options.CurrencyStrip && isNumericKind(f.Kind()) && f.Type().String() != "time.Duration" {
+		value = stripCurrency(options.CurrencySymbols, value)
+	}
+	if options.Locale != "" && isNumericKind(f.Kind()) && f.Type().String() != "time.Duration" {
+		v, err := delocalizeNumber(options.Locale, value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
 		}
+		value = v
+	}
 
-		switch f.Type().String() {
-		case "int":
-			k, err := cast.ToInt64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetInt(k)
-		case "int8":
-			k, err := cast.ToInt64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetInt(k)
-		case "int16":
-			k, err := cast.ToInt64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetInt(k)
-		case "int32":
-			k, err := cast.ToInt64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetInt(k)
-		case "int64":
-			k, err := cast.ToInt64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetInt(k)
-		case "uint":
-			k, err := cast.ToUint64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetUint(k)
-		case "uint8":
-			k, err := cast.ToUint64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetUint(k)
-		case "uint16":
-			k, err := cast.ToUint64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetUint(k)
-		case "uint32":
-			k, err := cast.ToUint64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetUint(k)
-		case "uint64":
-			k, err := cast.ToUint64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetUint(k)
-		case "float32":
-			k, err := cast.ToFloat64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetFloat(k)
-		case "float64":
-			k, err := cast.ToFloat64E(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetFloat(k)
-		case "string":
-			f.SetString(record[i])
-		case "bool":
-			k, err := cast.ToBoolE(record[i])
-			if !options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.SetBool(k)
-		case "time.Time":
-			t, err := time.Parse(time.RFC3339, record[i])
-			if options.IgnoreFieldTypeErrors && err != nil {
-				return fmt.Errorf("field %s type conversion failed: %s", headers[i], err)
-			}
-			f.Set(reflect.ValueOf(&t))
+	if value == "" && isNumericKind(f.Kind()) {
+		switch options.EmptyNumericPolicy {
+		case EmptyNumericZero:
+			f.Set(reflect.Zero(f.Type()))
+			return nil
+		case EmptyNumericSkip:
+			return nil
 		default:
-			if options != nil {
-				if options.CustomMarshallingFuncMap != nil {
-					if function, ok := options.CustomMarshallingFuncMap[f.Type().String()]; ok {
-						err := function(&f, record[i])
-						if options.IgnoreFieldTypeErrors && err != nil {
-							return fmt.Errorf("field %s type conversion failed for %s: %s", headers[i], f.Type().String(), err)
-						}
-					} else {
-						return fmt.Errorf("no custom unmarshalling function found for type %s", f.Type().String())
-					}
-				}
+			if !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed: empty value", header)
+			}
+			return nil
+		}
+	}
+
+	if _, enum := options.EnumMap[f.Type().String()]; isIntegerKind(f.Kind()) && !enum && f.Type().String() != "time.Duration" {
+		if trimmed, ok := trimLeadingZeros(value); ok {
+			if options.StrictLeadingZeros && !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed: leading zero in %q would be lost", header, value)
+			}
+			if options.WarnOnLeadingZeroLoss && options.Stats != nil {
+				options.Stats.LeadingZeroLosses++
 			}
+			value = trimmed
+		}
+	}
 
+	switch conversionName(options, f.Type()) {
+	case "int", "int8", "int16", "int32", "int64":
+		k, err := castInt64(value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		if f.OverflowInt(k) {
+			if !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed: value %d overflows %d-bit %s", header, k, f.Type().Bits(), f.Type().String())
+			}
+			return nil
+		}
+		f.SetInt(k)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		if strings.HasPrefix(strings.TrimSpace(value), "-") {
+			if !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed: negative value %s for unsigned type %s", header, value, f.Type().String())
+			}
+			return nil
+		}
+		k, err := castUint64(value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		if f.OverflowUint(k) {
+			if !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed: value %d overflows %d-bit %s", header, k, f.Type().Bits(), f.Type().String())
+			}
+			return nil
+		}
+		f.SetUint(k)
+	case "float32", "float64":
+		k, err := castFloat64(value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		if math.IsNaN(k) || math.IsInf(k, 0) {
+			switch options.FloatSpecialPolicy {
+			case FloatSpecialReject:
+				if !options.ignoreTypeError() {
+					return fmt.Errorf("field %s type conversion failed: non-finite value %s", header, value)
+				}
+				return nil
+			case FloatSpecialZero:
+				k = 0
+			}
+		}
+		f.SetFloat(k)
+	case "string":
+		f.SetString(value)
+	case "[]uint8":
+		f.SetBytes([]byte(value))
+	case "bool":
+		k, err := parseBool(options, value)
+		if err != nil && options.LenientBool {
+			k, err = parseNumericBool(value)
+		}
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		f.SetBool(k)
+	case "time.Duration":
+		d, err := parseDuration(options.DurationFormat, options.DurationUnit, value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		f.SetInt(int64(d))
+	case "time.Time":
+		t, err := parseTime(options, value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		f.Set(reflect.ValueOf(t))
+	case "net.IP":
+		if value == "" {
+			f.Set(reflect.Zero(f.Type()))
+			return nil
+		}
+		ip := net.ParseIP(value)
+		if ip == nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: invalid IP address %q", header, value)
+		}
+		f.Set(reflect.ValueOf(ip))
+	case "net.HardwareAddr":
+		if value == "" {
+			f.Set(reflect.Zero(f.Type()))
+			return nil
+		}
+		mac, err := net.ParseMAC(value)
+		if err != nil && !options.ignoreTypeError() {
+			return fmt.Errorf("field %s type conversion failed: %w", header, err)
+		}
+		f.Set(reflect.ValueOf(mac))
+	default:
+		if labels, ok := options.EnumMap[f.Type().String()]; ok && isIntegerKind(f.Kind()) {
+			k, ok := labels[value]
+			if !ok {
+				if !options.ignoreTypeError() {
+					return fmt.Errorf("field %s type conversion failed: unknown %s label %q", header, f.Type().String(), value)
+				}
+				return nil
+			}
+			if f.CanInt() {
+				f.SetInt(k)
+			} else {
+				f.SetUint(uint64(k))
+			}
+			return nil
+		}
+		if function, ok := options.CustomMarshallingFuncMap[f.Type().String()]; ok {
+			err := function(&f, value)
+			if err != nil && !options.ignoreTypeError() {
+				return fmt.Errorf("field %s type conversion failed for %s: %w", header, f.Type().String(), err)
+			}
+		} else if parse, ok := registeredParser(f.Type()); ok {
+			v, err := parse(value)
+			if err != nil {
+				if !options.ignoreTypeError() {
+					return fmt.Errorf("field %s type conversion failed for %s: %w", header, f.Type().String(), err)
+				}
+				return nil
+			}
+			f.Set(v)
+		} else if !options.SkipUnsupportedTypes {
+			return &UnknownTypeError{Type: f.Type().String(), Field: header}
 		}
 	}
 	return nil
 }
 
+// conversionName returns the name of the conversion unmarshalField applies to a field of type t: the name of t, or,
+// for a named bool, numeric or string type with no EnumMap entry, custom function or registered parser, the name of
+// its kind, so that such a type is read like its underlying type.
+func conversionName(options *Options, t reflect.Type) string {
+	name := t.String()
+	if t.PkgPath() == "" || name == "time.Duration" || (!isNumericKind(t.Kind()) && t.Kind() != reflect.String && t.Kind() != reflect.Bool) {
+		return name
+	}
+	if _, ok := options.EnumMap[name]; ok && isIntegerKind(t.Kind()) {
+		return name
+	}
+	if _, ok := options.CustomMarshallingFuncMap[name]; ok {
+		return name
+	}
+	if _, ok := registeredParser(t); ok {
+		return name
+	}
+	return t.Kind().String()
+}
+
+// parseBool parses value as a bool. CheckboxBool treats any non-empty value as true; otherwise BoolTrueValues and
+// BoolFalseValues are consulted before cast. Integers other than 0 and 1 are an error.
+func parseBool(options *Options, value string) (bool, error) {
+	if options.CheckboxBool {
+		return value != "", nil
+	}
+	for _, token := range options.BoolTrueValues {
+		if value == token {
+			return true, nil
+		}
+	}
+	for _, token := range options.BoolFalseValues {
+		if value == token {
+			return false, nil
+		}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n != 0 && n != 1 {
+		return false, fmt.Errorf("unable to parse %q as bool: integer value is neither 0 nor 1", value)
+	}
+	return cast.ToBoolE(value)
+}
+
+// castInt64 converts value with cast, returning the *strconv.NumError for value when it fails so callers can
+// inspect it with errors.As.
+func castInt64(value string) (int64, error) {
+	k, err := cast.ToInt64E(value)
+	if err != nil {
+		if _, perr := strconv.ParseInt(value, 10, 64); perr != nil {
+			return 0, perr
+		}
+	}
+	return k, err
+}
+
+// castUint64 is castInt64 for unsigned values. Values above math.MaxInt64, which cast rejects, are parsed with
+// strconv.
+func castUint64(value string) (uint64, error) {
+	k, err := cast.ToUint64E(value)
+	if err != nil {
+		u, perr := strconv.ParseUint(value, 10, 64)
+		if perr != nil {
+			return 0, perr
+		}
+		return u, nil
+	}
+	return k, nil
+}
+
+// castFloat64 is castInt64 for floating point values.
+func castFloat64(value string) (float64, error) {
+	k, err := cast.ToFloat64E(value)
+	if err != nil {
+		if _, perr := strconv.ParseFloat(value, 64); perr != nil {
+			return 0, perr
+		}
+	}
+	return k, err
+}
+
+// parseNumericBool treats float values within epsilon of 1 or 0 as true or false.
+func parseNumericBool(s string) (bool, error) {
+	const epsilon = 1e-9
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse %q as bool", s)
+	}
+	switch {
+	case math.Abs(n-1) < epsilon:
+		return true, nil
+	case math.Abs(n) < epsilon:
+		return false, nil
+	}
+	return false, fmt.Errorf("unable to parse %q as bool: numeric value is neither 0 nor 1", s)
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// trimLeadingZeros removes the leading zeros of a decimal integer such as "07030", which would otherwise be read as
+// octal, keeping any sign. ok is false when s has no leading zero before another digit.
+func trimLeadingZeros(s string) (string, bool) {
+	sign := ""
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	if len(s) < 2 || s[0] != '0' {
+		return "", false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	trimmed := strings.TrimLeft(s, "0")
+	if trimmed == "" {
+		trimmed = "0"
+	}
+	return sign + trimmed, true
+}
+
+// stripOuterQuotes removes one pair of matching double quotes surrounding s.
+func stripOuterQuotes(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// findControlChar returns the first disallowed control character in s. Tab, carriage return and newline are allowed.
+func findControlChar(s string) (rune, bool) {
+	for _, c := range s {
+		switch {
+		case c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '\uFEFF' || unicode.IsControl(c):
+			return c, true
+		}
+	}
+	return 0, false
+}
+
 func getFieldNameFromStructTag(tag, key string, s interface{}) (string, error) {
 	var rt reflect.Type
 	if reflect.TypeOf(s).Kind() == reflect.Ptr {
@@ -0,0 +1,1062 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type logLine struct {
+	Level   string
+	Code    int
+	Comment string
+}
+
+func TestJoinTrailingInto(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		content string
+		want    string
+	}{
+		{"comment with commas", Options{}, "Level,Code,Comment\nwarn,3,disk low, retry later, maybe\n", "disk low, retry later, maybe"},
+		{"exact width", Options{}, "Level,Code,Comment\nwarn,3,done\n", "done"},
+		{"semicolon separator", Options{Separator: ';'}, "Level;Code;Comment\nwarn;3;a;b\n", "a;b"},
+		{"string separator", Options{StringSeparator: "||"}, "Level||Code||Comment\nwarn||3||a||b||c\n", "a||b||c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.JoinTrailingInto = "Comment"
+			got, err := ProcessCSV[logLine](&options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Comment != tt.want || got[0].Code != 3 {
+				t.Errorf("got %+v, want comment %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinTrailingIntoNotLast(t *testing.T) {
+	if _, err := ProcessCSV[logLine](&Options{JoinTrailingInto: "Level"}, "Level,Code,Comment\nwarn,3,x\n"); err == nil {
+		t.Error("expected an error for a field not bound to the last column")
+	}
+}
+
+type narrowInts struct {
+	I8  int8
+	I16 int16
+	I32 int32
+	U8  uint8
+	U16 uint16
+	U32 uint32
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		header, value string
+	}{
+		{"I8", "300"},
+		{"I8", "-129"},
+		{"I16", "32768"},
+		{"I32", "2147483648"},
+		{"U8", "256"},
+		{"U16", "70000"},
+		{"U32", "4294967296"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header+"="+tt.value, func(t *testing.T) {
+			content := tt.header + "\n" + tt.value + "\n"
+			if _, err := ProcessCSV[narrowInts](nil, content); err == nil || !strings.Contains(err.Error(), "overflows") {
+				t.Errorf("got error %v, want an overflow error", err)
+			}
+			got, err := ProcessCSV[narrowInts](&Options{IgnoreFieldTypeErrors: true}, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got[0] != (narrowInts{}) {
+				t.Errorf("got %+v, want the field left zero", *got[0])
+			}
+		})
+	}
+}
+
+func TestIntegerLimits(t *testing.T) {
+	got, err := ProcessCSV[narrowInts](nil, "I8,I16,I32,U8,U16,U32\n-128,32767,-2147483648,255,65535,4294967295\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := narrowInts{-128, 32767, -2147483648, 255, 65535, 4294967295}
+	if *got[0] != want {
+		t.Errorf("got %+v, want %+v", *got[0], want)
+	}
+}
+
+type floats struct {
+	F   float64
+	F32 float32
+}
+
+func TestFloatSpecialPolicy(t *testing.T) {
+	tests := []struct {
+		policy  FloatSpecialPolicy
+		cell    string
+		want    float64
+		wantErr bool
+	}{
+		{FloatSpecialAllow, "NaN", math.NaN(), false},
+		{FloatSpecialAllow, "Inf", math.Inf(1), false},
+		{FloatSpecialAllow, "-Inf", math.Inf(-1), false},
+		{FloatSpecialReject, "NaN", 0, true},
+		{FloatSpecialReject, "Inf", 0, true},
+		{FloatSpecialReject, "-Inf", 0, true},
+		{FloatSpecialReject, "1.5", 1.5, false},
+		{FloatSpecialZero, "NaN", 0, false},
+		{FloatSpecialZero, "-Inf", 0, false},
+		{FloatSpecialZero, "2.5", 2.5, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%s", tt.policy, tt.cell), func(t *testing.T) {
+			options := &Options{FloatSpecialPolicy: tt.policy}
+			got, err := ProcessCSV[floats](options, "F,F32\n"+tt.cell+","+tt.cell+"\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected a non-finite value error")
+				}
+				if _, err := ProcessCSV[floats](&Options{FloatSpecialPolicy: tt.policy, IgnoreFieldTypeErrors: true}, "F\n"+tt.cell+"\n"); err != nil {
+					t.Errorf("error not ignored with IgnoreFieldTypeErrors: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []float64{got[0].F, float64(got[0].F32)} {
+				if f != tt.want && !(math.IsNaN(f) && math.IsNaN(tt.want)) {
+					t.Errorf("got %v, want %v", f, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessCSVEmptyAndHeaderOnly(t *testing.T) {
+	got, err := ProcessCSV[pair](nil, "")
+	if err != nil || got != nil {
+		t.Errorf("empty input: got %#v, %v, want a nil slice", got, err)
+	}
+	for _, content := range []string{"A,B\n", "A,B"} {
+		got, err := ProcessCSV[pair](nil, content)
+		if err != nil || got == nil || len(got) != 0 {
+			t.Errorf("header-only input %q: got %#v, %v, want an empty non-nil slice", content, got, err)
+		}
+	}
+}
+
+type quotedNumbers struct {
+	I int
+	F float64
+	U uint8
+}
+
+func TestStripOuterQuotes(t *testing.T) {
+	options := &Options{LazyQuotes: true, StripOuterQuotes: true}
+	got, err := ProcessCSV[quotedNumbers](options, "I,F,U\n\"\"\"42\"\"\",\"\"\"1.5\"\"\",\"\"\"7\"\"\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (quotedNumbers{42, 1.5, 7}); *got[0] != want {
+		t.Errorf("got %+v, want %+v", *got[0], want)
+	}
+
+	got, err = ProcessCSV[quotedNumbers](options, "I,F,U\nx\"42\",3,1\n")
+	if err == nil {
+		t.Errorf("got %+v, want an error for unmatched quotes", *got[0])
+	}
+	if _, err := ProcessCSV[quotedNumbers](&Options{LazyQuotes: true}, "I,F,U\n\"\"\"42\"\"\",1,1\n"); err == nil {
+		t.Error("expected an error without StripOuterQuotes")
+	}
+}
+
+func TestEmptyNumericPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+		want    quotedNumbers
+	}{
+		{"error", Options{EmptyNumericPolicy: EmptyNumericError}, true, quotedNumbers{}},
+		{"error ignored", Options{EmptyNumericPolicy: EmptyNumericError, IgnoreFieldTypeErrors: true}, false, quotedNumbers{I: -1, F: -1, U: 9}},
+		{"zero", Options{EmptyNumericPolicy: EmptyNumericZero}, false, quotedNumbers{}},
+		{"skip", Options{EmptyNumericPolicy: EmptyNumericSkip}, false, quotedNumbers{I: -1, F: -1, U: 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			v := quotedNumbers{I: -1, F: -1, U: 9}
+			err := UnmarshalRecord(&options, []string{"I", "F", "U"}, []string{"", "", ""}, &v)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an empty value error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tt.want {
+				t.Errorf("got %+v, want %+v", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessCSVFilter(t *testing.T) {
+	content := "Level,Code,Comment\ninfo,1,a\nwarn,2,b\nerror,3,c\nwarn,4,d\n"
+	var seen []int
+	got, err := ProcessCSVFilter[logLine](nil, content, func(l *logLine) bool {
+		seen = append(seen, l.Code)
+		return l.Level == "warn"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Code != 2 || got[1].Code != 4 {
+		t.Errorf("got %+v, want the warn rows", got)
+	}
+	if len(seen) != 4 {
+		t.Errorf("keep saw %v, want every fully bound row", seen)
+	}
+
+	got, err = ProcessCSVFilter[logLine](nil, content, func(*logLine) bool { return false })
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("got %#v, %v, want an empty slice when nothing is kept", got, err)
+	}
+}
+
+type celsius struct {
+	Degrees float64
+}
+
+type reading struct {
+	Temp celsius
+}
+
+func TestCustomMarshallingFuncError(t *testing.T) {
+	errBad := errors.New("bad reading")
+	options := func(ignore bool) *Options {
+		return &Options{
+			IgnoreFieldTypeErrors: ignore,
+			CustomMarshallingFuncMap: map[string]CustomMarshallingFunc{
+				"csv.celsius": func(v *reflect.Value, fieldValue string) error {
+					if !strings.HasSuffix(fieldValue, "C") {
+						return errBad
+					}
+					d, err := strconv.ParseFloat(strings.TrimSuffix(fieldValue, "C"), 64)
+					v.Set(reflect.ValueOf(celsius{d}))
+					return err
+				},
+			},
+		}
+	}
+
+	got, err := ProcessCSV[reading](options(false), "Temp\n21.5C\n")
+	if err != nil || got[0].Temp.Degrees != 21.5 {
+		t.Fatalf("got %v, %v, want 21.5", got, err)
+	}
+	if _, err := ProcessCSV[reading](options(false), "Temp\n70F\n"); !errors.Is(err, errBad) {
+		t.Errorf("got error %v, want the custom function's error", err)
+	}
+	got, err = ProcessCSV[reading](options(true), "Temp\n70F\n")
+	if err != nil || got[0].Temp.Degrees != 0 {
+		t.Errorf("with IgnoreFieldTypeErrors got %v, %v, want the error ignored", got, err)
+	}
+}
+
+type flag struct {
+	On bool
+}
+
+func TestLenientBool(t *testing.T) {
+	tests := []struct {
+		cell    string
+		want    bool
+		wantErr bool
+	}{
+		{"1.0", true, false},
+		{"0.0", false, false},
+		{"1.00000", true, false},
+		{"true", true, false},
+		{"0.5", false, true},
+		{"2.0", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cell, func(t *testing.T) {
+			got, err := ProcessCSV[flag](&Options{LenientBool: true}, "On\n"+tt.cell+"\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got[0].On)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].On != tt.want {
+				t.Errorf("got %v, want %v", got[0].On, tt.want)
+			}
+		})
+	}
+	if _, err := ProcessCSV[flag](nil, "On\n1.0\n"); err == nil {
+		t.Error("expected an error for 1.0 without LenientBool")
+	}
+}
+
+func TestBoolIntegers(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		cell    string
+		want    bool
+		wantErr bool
+	}{
+		{"one", Options{}, "1", true, false},
+		{"zero", Options{}, "0", false, false},
+		{"two", Options{}, "2", false, true},
+		{"minus one", Options{}, "-1", false, true},
+		{"two ignored", Options{IgnoreFieldTypeErrors: true}, "2", false, false},
+		{"minus one ignored", Options{IgnoreFieldTypeErrors: true}, "-1", false, false},
+		{"configured token", Options{BoolTrueValues: []string{"2"}}, "2", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[flag](&tt.options, "On\n"+tt.cell+"\n")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "neither 0 nor 1") {
+					t.Fatalf("got error %v, want one rejecting the integer", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].On != tt.want {
+				t.Errorf("got %v, want %v", got[0].On, tt.want)
+			}
+		})
+	}
+}
+
+type status int
+
+const (
+	statusInactive status = iota
+	statusActive
+)
+
+type account struct {
+	Name   string
+	Status status
+}
+
+func TestEnumMap(t *testing.T) {
+	options := func() *Options {
+		return &Options{EnumMap: map[string]map[string]int64{
+			"csv.status": {"INACTIVE": int64(statusInactive), "ACTIVE": int64(statusActive)},
+		}}
+	}
+	got, err := ProcessCSV[account](options(), "Name,Status\na,ACTIVE\nb,INACTIVE\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []status{statusActive, statusInactive} {
+		if got[i].Status != want {
+			t.Errorf("row %d = %v, want %v", i, got[i].Status, want)
+		}
+	}
+	if _, err := ProcessCSV[account](options(), "Name,Status\na,DELETED\n"); err == nil {
+		t.Error("expected an error for an unknown label")
+	}
+}
+
+type point3 struct {
+	Coords [3]float64
+}
+
+func TestArrayFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		cell    string
+		want    [3]float64
+		wantErr bool
+	}{
+		{"exact", "1;2;3.5", [3]float64{1, 2, 3.5}, false},
+		{"too few", "1;2", [3]float64{}, true},
+		{"too many", "1;2;3;4", [3]float64{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[point3](nil, "Coords\n"+tt.cell+"\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an element count error", got[0].Coords)
+				}
+				if _, err := ProcessCSV[point3](&Options{IgnoreFieldTypeErrors: true}, "Coords\n"+tt.cell+"\n"); err != nil {
+					t.Errorf("error not ignored with IgnoreFieldTypeErrors: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].Coords != tt.want {
+				t.Errorf("got %v, want %v", got[0].Coords, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		line    string
+		want    logLine
+	}{
+		{"comma", nil, `warn,3,"disk low, retry"`, logLine{"warn", 3, "disk low, retry"}},
+		{"separator", &Options{Separator: ';'}, "warn;3;a,b", logLine{"warn", 3, "a,b"}},
+		{"trim", &Options{TrimLeadingSpace: true}, "warn, 3,  done", logLine{"warn", 3, "done"}},
+		{"short", nil, "warn,3", logLine{"warn", 3, ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got logLine
+			if err := UnmarshalLine(tt.options, tt.line, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	var got logLine
+	if err := UnmarshalLine(nil, "warn,3,a,extra", &got); err == nil {
+		t.Error("expected an error for more fields than the struct has")
+	}
+}
+
+type unsigned struct {
+	U8  uint8
+	U64 uint64
+}
+
+func TestNegativeUnsigned(t *testing.T) {
+	for _, header := range []string{"U8", "U64"} {
+		t.Run(header, func(t *testing.T) {
+			content := header + "\n-1\n"
+			_, err := ProcessCSV[unsigned](nil, content)
+			if err == nil || !strings.Contains(err.Error(), "negative value") {
+				t.Errorf("got error %v, want a negative value error", err)
+			}
+			got, err := ProcessCSV[unsigned](&Options{IgnoreFieldTypeErrors: true}, content)
+			if err != nil || *got[0] != (unsigned{}) {
+				t.Errorf("with IgnoreFieldTypeErrors got %v, %v, want the field left zero", got, err)
+			}
+		})
+	}
+	got, err := ProcessCSV[unsigned](nil, "U8,U64\n0,18446744073709551615\n")
+	if err != nil || got[0].U64 != math.MaxUint64 {
+		t.Errorf("got %v, %v, want the largest uint64", got, err)
+	}
+}
+
+func TestEnforceHeaderWidth(t *testing.T) {
+	options := &Options{EnforceHeaderWidth: true}
+	_, err := ProcessCSV[logLine](options, "Level,Code,Comment\ninfo,1,a\nwarn,2,b,extra\n")
+	if err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("wide row: got error %v, want one naming row 2", err)
+	}
+
+	got, err := ProcessCSV[logLine](options, "Level,Code,Comment\ninfo,1\nwarn,2,b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || *got[0] != (logLine{"info", 1, ""}) || *got[1] != (logLine{"warn", 2, "b"}) {
+		t.Errorf("narrow row: got %+v", got)
+	}
+
+	got, err = ProcessCSV[logLine](&Options{EnforceHeaderWidth: true, CollectErrors: true}, "Level,Code,Comment\ninfo,1,a,x\nwarn,2,b\n")
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) || len(rowErrs) != 1 || rowErrs[0].Row != 1 || len(got) != 1 {
+		t.Errorf("collected: got %+v, %v", got, err)
+	}
+}
+
+func TestBoolTokensAndCheckbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		cell    string
+		want    bool
+		wantErr bool
+	}{
+		{"check glyph", Options{BoolTrueValues: []string{"✓"}, BoolFalseValues: []string{"✗"}}, "✓", true, false},
+		{"cross glyph", Options{BoolTrueValues: []string{"✓"}, BoolFalseValues: []string{"✗"}}, "✗", false, false},
+		{"yes token", Options{BoolTrueValues: []string{"yes"}}, "yes", true, false},
+		{"unknown glyph", Options{BoolTrueValues: []string{"✓"}}, "?", false, true},
+		{"checkbox marked", Options{CheckboxBool: true}, "x", true, false},
+		{"checkbox glyph", Options{CheckboxBool: true}, "✓", true, false},
+		{"checkbox empty", Options{CheckboxBool: true}, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			var got flag
+			err := UnmarshalRecord(&options, []string{"On"}, []string{tt.cell}, &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got.On)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.On != tt.want {
+				t.Errorf("got %v, want %v", got.On, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopFunc(t *testing.T) {
+	var calls int
+	options := &Options{StopFunc: func(headers, record []string) bool {
+		calls++
+		return record[0] == "TOTAL"
+	}}
+	got, err := ProcessCSV[logLine](options, "Level,Code,Comment\ninfo,1,a\nwarn,2,b\nTOTAL,x,not a row\ninfo,3,c\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Code != 2 {
+		t.Errorf("got %+v, want the rows before the sentinel", got)
+	}
+	if calls != 3 {
+		t.Errorf("StopFunc called %d times, want 3", calls)
+	}
+}
+
+func TestUnsupportedType(t *testing.T) {
+	for _, funcs := range []map[string]CustomMarshallingFunc{nil, {}} {
+		_, err := ProcessCSV[reading](&Options{CustomMarshallingFuncMap: funcs}, "Temp\n21C\n")
+		var unknown *UnknownTypeError
+		if !errors.As(err, &unknown) || unknown.Type != "csv.celsius" || unknown.Field != "Temp" {
+			t.Errorf("func map %v: got error %v, want an UnknownTypeError for Temp", funcs, err)
+		}
+
+		got, err := ProcessCSV[reading](&Options{CustomMarshallingFuncMap: funcs, SkipUnsupportedTypes: true}, "Temp\n21C\n")
+		if err != nil || got[0].Temp != (celsius{}) {
+			t.Errorf("func map %v with SkipUnsupportedTypes: got %v, %v, want the field left zero", funcs, got, err)
+		}
+	}
+}
+
+func TestProcessCSVOnRecord(t *testing.T) {
+	var indexes []int
+	var codes []int
+	got, err := ProcessCSVOnRecord[logLine](nil, "Level,Code,Comment\ninfo,1,a\nwarn,2,b\nerror,3,c\n", func(index int, v *logLine) {
+		indexes = append(indexes, index)
+		codes = append(codes, v.Code)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || !reflect.DeepEqual(indexes, []int{0, 1, 2}) || !reflect.DeepEqual(codes, []int{1, 2, 3}) {
+		t.Errorf("got %d rows, indexes %v and codes %v", len(got), indexes, codes)
+	}
+
+	calls := 0
+	_, err = ProcessCSVOnRecord[logLine](nil, "Level,Code,Comment\ninfo,1,a\nwarn,x,b\n", func(int, *logLine) { calls++ })
+	if err == nil || calls != 1 {
+		t.Errorf("got %d calls and error %v, want one call before the failing row", calls, err)
+	}
+}
+
+func TestCommentHeaderOnly(t *testing.T) {
+	content := "# exported today\nLevel,Code,Comment\ninfo,1,a\n#warn,2,b\n"
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{"comments everywhere", Options{Comment: '#'}, []string{"info"}},
+		{"comments in header only", Options{Comment: '#', CommentHeaderOnly: true}, []string{"info", "#warn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[logLine](&tt.options, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var levels []string
+			for _, l := range got {
+				levels = append(levels, l.Level)
+			}
+			if !reflect.DeepEqual(levels, tt.want) {
+				t.Errorf("got levels %q, want %q", levels, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentHeaderOnlyHeaderOverride(t *testing.T) {
+	options := &Options{Comment: '#', CommentHeaderOnly: true, HeaderOverride: []string{"Level", "Code", "Comment"}}
+	content := "# exported today\ninfo,1,a\n#warn,2,b\n"
+	want := []string{"info", "#warn"}
+
+	rows, err := ProcessCSV[logLine](options, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var levels []string
+	for _, l := range rows {
+		levels = append(levels, l.Level)
+	}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("ProcessCSV: got levels %q, want %q", levels, want)
+	}
+
+	_, maps, err := ProcessCSVGeneric(options, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels = nil
+	for _, m := range maps {
+		levels = append(levels, m["Level"])
+	}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("ProcessCSVGeneric: got levels %q, want %q", levels, want)
+	}
+
+	construct := func() any { return &logLine{} }
+	vs, err := ProcessPolymorphic(options, content, "Level", map[string]func() any{"info": construct, "#warn": construct})
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels = nil
+	for _, v := range vs {
+		levels = append(levels, v.(*logLine).Level)
+	}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("ProcessPolymorphic: got levels %q, want %q", levels, want)
+	}
+}
+
+type optionalCollections struct {
+	Tags    *[]string
+	Attrs   *map[string]string
+	Scores  map[string]int
+	Aliases []string
+}
+
+func TestPointerCollectionFields(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+		want optionalCollections
+	}{
+		{"empty", ",,,", optionalCollections{}},
+		{"populated", "a;b,k=v;x=y,a=1,p;q", optionalCollections{
+			Tags:    &[]string{"a", "b"},
+			Attrs:   &map[string]string{"k": "v", "x": "y"},
+			Scores:  map[string]int{"a": 1},
+			Aliases: []string{"p", "q"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[optionalCollections](nil, "Tags,Attrs,Scores,Aliases\n"+tt.row+"\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got[0], tt.want) {
+				t.Errorf("got %+v, want %+v", *got[0], tt.want)
+			}
+		})
+	}
+
+	if _, err := ProcessCSV[optionalCollections](nil, "Tags,Attrs,Scores,Aliases\n,k,,\n"); err == nil {
+		t.Error("expected an error for a map entry without a key separator")
+	}
+}
+
+func TestProcessCSVMulti(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []string
+	}{
+		{"terminated parts", []string{"Level,Code,Comment\ninfo,1,a\n", "warn,2,b\n", "error,3,c\n"}},
+		{"unterminated parts", []string{"Level,Code,Comment\ninfo,1,a", "warn,2,b", "error,3,c"}},
+		{"CRLF parts", []string{"Level,Code,Comment\r\ninfo,1,a\r\n", "warn,2,b\r\n", "error,3,c"}},
+		{"empty part", []string{"Level,Code,Comment\ninfo,1,a", "", "warn,2,b\nerror,3,c\n"}},
+	}
+	want := []logLine{{"info", 1, "a"}, {"warn", 2, "b"}, {"error", 3, "c"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readers := make([]io.Reader, len(tt.parts))
+			for i, part := range tt.parts {
+				readers[i] = iotest.OneByteReader(strings.NewReader(part))
+			}
+			got, err := ProcessCSVMulti[logLine](nil, readers...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d rows, want %d", len(got), len(want))
+			}
+			for i := range got {
+				if *got[i] != want[i] {
+					t.Errorf("row %d = %+v, want %+v", i, *got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+type host struct {
+	Name string
+	IP   net.IP
+	MAC  net.HardwareAddr
+}
+
+func TestNetworkFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		row     string
+		want    host
+		wantErr bool
+	}{
+		{"IPv4 and MAC", Options{}, "web,10.0.0.1,00:1a:2b:3c:4d:5e", host{"web", net.ParseIP("10.0.0.1"), net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}}, false},
+		{"IPv6 and dashed MAC", Options{}, "db,::1,00-1A-2B-3C-4D-5E", host{"db", net.ParseIP("::1"), net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}}, false},
+		{"empty", Options{}, "x,,", host{Name: "x"}, false},
+		{"invalid MAC", Options{}, "x,10.0.0.1,zz:zz", host{}, true},
+		{"invalid IP", Options{}, "x,10.0.0.300,", host{}, true},
+		{"invalid MAC ignored", Options{IgnoreFieldTypeErrors: true}, "x,10.0.0.1,zz:zz", host{"x", net.ParseIP("10.0.0.1"), nil}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[host](&tt.options, "Name,IP,MAC\n"+tt.row+"\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want a parse error", *got[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := got[0]
+			if g.Name != tt.want.Name || !g.IP.Equal(tt.want.IP) || g.MAC.String() != tt.want.MAC.String() {
+				t.Errorf("got %+v, want %+v", *g, tt.want)
+			}
+		})
+	}
+}
+
+type withUnits struct {
+	Amount float64 `csv:"Amount (USD)"`
+	Count  int     `csv:"Count"`
+}
+
+func TestHeaderRows(t *testing.T) {
+	underscore := "_"
+	tests := []struct {
+		name    string
+		options Options
+		content string
+		want    map[string]string
+	}{
+		{"one row", Options{IgnoreUnknownFields: true}, "Amount,Count\n(USD),\n", map[string]string{"Count": "Count"}},
+		{"two rows", Options{HeaderRows: 2}, "Amount,Count\n(USD),\n", map[string]string{"Amount (USD)": "Amount", "Count": "Count"}},
+		{"custom join", Options{HeaderRows: 2, HeaderJoin: &underscore, IgnoreUnknownFields: true}, "Amount,Count\n(USD),\n", map[string]string{"Count": "Count"}},
+		{"three rows", Options{HeaderRows: 3}, "Amount,\n,Count\n(USD),\n", map[string]string{"Amount (USD)": "Amount", "Count": "Count"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.UseStructTags = true
+			got, err := ParseHeader[withUnits](&options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	got, err := ProcessCSV[withUnits](&Options{UseStructTags: true, HeaderRows: 2}, "Amount,Count\n(USD),\n1.5,2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || *got[0] != (withUnits{1.5, 2}) {
+		t.Errorf("got %+v, want one row bound to the merged header", got)
+	}
+
+	empty := ""
+	headers, _, err := ProcessCSVGeneric(&Options{HeaderRows: 2, HeaderJoin: &empty}, "Amount,Count\n(USD),\n1.5,2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Amount(USD)", "Count"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("got headers %q with an empty join, want %q", headers, want)
+	}
+}
+
+func TestCollectErrorsFieldCount(t *testing.T) {
+	content := "Level,Code,Comment\ninfo,1,a\nwarn,2\nerror,3,c\ndebug,4,d,x\nfatal,x,e\nok,6,f\n"
+	got, err := ProcessCSV[logLine](&Options{FieldsPerRecord: 3, CollectErrors: true}, content)
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("got error %v, want RowErrors", err)
+	}
+
+	want := []struct {
+		row, line int
+		msg       string
+	}{
+		{2, 3, "record has 2 fields, want 3"},
+		{4, 5, "record has 4 fields, want 3"},
+		{5, 0, "Code"},
+	}
+	if len(rowErrs) != len(want) {
+		t.Fatalf("got %d row errors, want %d: %v", len(rowErrs), len(want), err)
+	}
+	for i, w := range want {
+		e := rowErrs[i]
+		if e.Row != w.row || e.Line != w.line || !strings.Contains(e.Error(), w.msg) {
+			t.Errorf("error %d = row %d line %d %q, want row %d line %d containing %q", i, e.Row, e.Line, e.Error(), w.row, w.line, w.msg)
+		}
+	}
+	for _, e := range rowErrs[:2] {
+		if !errors.Is(e, csv.ErrFieldCount) {
+			t.Errorf("error %v does not wrap csv.ErrFieldCount", e)
+		}
+	}
+
+	var levels []string
+	for _, l := range got {
+		levels = append(levels, l.Level)
+	}
+	if !reflect.DeepEqual(levels, []string{"info", "error", "ok"}) {
+		t.Errorf("got rows %q, want the rows that parsed", levels)
+	}
+
+	if _, err := ProcessCSV[logLine](&Options{FieldsPerRecord: 3}, content); err == nil || errors.As(err, &rowErrs) {
+		t.Errorf("got error %v, want the parse aborted without CollectErrors", err)
+	}
+}
+
+type zipped struct {
+	Zip int
+}
+
+func TestLeadingZeroLoss(t *testing.T) {
+	content := "Zip\n07030\n12345\n010\n"
+	tests := []struct {
+		name       string
+		options    Options
+		want       []int
+		wantLosses int
+		wantErr    bool
+	}{
+		{"default", Options{}, []int{7030, 12345, 10}, 0, false},
+		{"warn", Options{WarnOnLeadingZeroLoss: true}, []int{7030, 12345, 10}, 2, false},
+		{"strict", Options{StrictLeadingZeros: true}, nil, 0, true},
+		{"strict ignored", Options{StrictLeadingZeros: true, IgnoreFieldTypeErrors: true}, []int{7030, 12345, 10}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &ProcessStats{}
+			options := tt.options
+			options.Stats = stats
+			got, err := ProcessCSV[zipped](&options, content)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "07030") {
+					t.Fatalf("got error %v, want one quoting 07030", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var zips []int
+			for _, z := range got {
+				zips = append(zips, z.Zip)
+			}
+			if !reflect.DeepEqual(zips, tt.want) {
+				t.Errorf("got %v, want %v", zips, tt.want)
+			}
+			if stats.LeadingZeroLosses != tt.wantLosses {
+				t.Errorf("got %d leading zero losses, want %d", stats.LeadingZeroLosses, tt.wantLosses)
+			}
+		})
+	}
+}
+
+func TestDefaultOptions(t *testing.T) {
+	options := DefaultOptions()
+	if options.Separator != ',' || options.Comment != '#' || options.FieldsPerRecord != -1 {
+		t.Errorf("got Separator %q, Comment %q, FieldsPerRecord %d, want ',', '#', -1", options.Separator, options.Comment, options.FieldsPerRecord)
+	}
+	if !options.UseFieldNames || options.UseStructTags {
+		t.Errorf("got UseFieldNames %v, UseStructTags %v, want field names", options.UseFieldNames, options.UseStructTags)
+	}
+	if options.CustomMarshallingFuncMap == nil || len(options.CustomMarshallingFuncMap) != 0 {
+		t.Errorf("got CustomMarshallingFuncMap %v, want an empty map", options.CustomMarshallingFuncMap)
+	}
+
+	options.Separator = ';'
+	options.CustomMarshallingFuncMap["main.T"] = func(v *reflect.Value, fieldValue string) error { return nil }
+	fresh := DefaultOptions()
+	if fresh == options || fresh.Separator != ',' || len(fresh.CustomMarshallingFuncMap) != 0 {
+		t.Error("DefaultOptions returned options sharing state with an earlier call")
+	}
+
+	got, err := ProcessCSV[pair](DefaultOptions(), "# comment\nA,B\na,b\nc\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || *got[0] != (pair{"a", "b"}) || *got[1] != (pair{A: "c"}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestProcessStats(t *testing.T) {
+	type row struct {
+		Name string
+		Age  int
+	}
+	stats := &ProcessStats{}
+	options := &Options{RepairQuotes: true, IgnoreUnknownFields: true, IgnoreFieldTypeErrors: true, Stats: stats}
+	content := "Name,Age,Extra\nann,30,x\nb\"ob,x,y\ncat,5,z\n"
+
+	got, err := ProcessCSV[row](options, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[1].Name != `b"ob` {
+		t.Fatalf("got %+v", got)
+	}
+	want := ProcessStats{Rows: 3, SkippedFields: 3, CoercedValues: 1, RepairedRows: 1}
+	if *stats != want {
+		t.Errorf("got %+v, want %+v", *stats, want)
+	}
+
+	if _, err := ProcessCSV[row](options, content); err != nil {
+		t.Fatal(err)
+	}
+	want = ProcessStats{Rows: 6, SkippedFields: 6, CoercedValues: 2, RepairedRows: 2}
+	if *stats != want {
+		t.Errorf("got %+v after a second parse, want the counters accumulated to %+v", *stats, want)
+	}
+}
+
+func TestMinMaxFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		row     string
+		want    logLine
+		wantErr string
+	}{
+		{"below min", Options{MinFields: 2, MaxFields: 4}, "warn", logLine{}, "record has 1 fields, want 2 to 4"},
+		{"at min", Options{MinFields: 2, MaxFields: 4}, "warn,3", logLine{"warn", 3, ""}, ""},
+		{"header width", Options{MinFields: 2, MaxFields: 4}, "warn,3,a", logLine{"warn", 3, "a"}, ""},
+		{"at max", Options{MinFields: 2, MaxFields: 4}, "warn,3,a,b", logLine{"warn", 3, "a"}, ""},
+		{"above max", Options{MinFields: 2, MaxFields: 4}, "warn,3,a,b,c", logLine{}, "record has 5 fields, want 2 to 4"},
+		{"at max joined", Options{MinFields: 2, MaxFields: 4, JoinTrailingInto: "Comment"}, "warn,3,a,b", logLine{"warn", 3, "a,b"}, ""},
+		{"min only", Options{MinFields: 3}, "warn,3", logLine{}, "record has 2 fields, want at least 3"},
+		{"max only", Options{MaxFields: 3}, "warn,3,a,b", logLine{}, "record has 4 fields, want at most 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			got, err := ProcessCSV[logLine](&options, "Level,Code,Comment\n"+tt.row+"\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+
+				options.CollectErrors = true
+				got, err = ProcessCSV[logLine](&options, "Level,Code,Comment\n"+tt.row+"\ninfo,1,x\n")
+				var rowErrs RowErrors
+				if !errors.As(err, &rowErrs) || len(rowErrs) != 1 || len(got) != 1 {
+					t.Errorf("with CollectErrors got %d rows and error %v, want the row collected", len(got), err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got[0] != tt.want {
+				t.Errorf("got %+v, want %+v", *got[0], tt.want)
+			}
+		})
+	}
+}
+
+type typedRow struct {
+	I int
+	U uint16
+	F float64
+}
+
+func TestErrorChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		row      string
+		wantFunc string
+		wantNum  string
+	}{
+		{"int", "x,1,1.5", "ParseInt", "x"},
+		{"uint", "1,3x,1.5", "ParseUint", "3x"},
+		{"float", "1,1,abc", "ParseFloat", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProcessCSV[typedRow](nil, "I,U,F\n"+tt.row+"\n")
+			var numErr *strconv.NumError
+			if !errors.As(err, &numErr) {
+				t.Fatalf("errors.As(%v) found no *strconv.NumError", err)
+			}
+			if numErr.Func != tt.wantFunc || numErr.Num != tt.wantNum || !errors.Is(err, strconv.ErrSyntax) {
+				t.Errorf("got %#v, want a %s syntax error for %q", numErr, tt.wantFunc, tt.wantNum)
+			}
+		})
+	}
+
+	_, err := ProcessCSV[typedRow](&Options{FieldsPerRecord: 3}, "I,U,F\n1,2,3\n4,5\n")
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || parseErr.StartLine != 3 || !errors.Is(err, csv.ErrFieldCount) {
+		t.Errorf("got error %v, want a *csv.ParseError for line 3 wrapping csv.ErrFieldCount", err)
+	}
+
+	err = UnmarshalRecord(nil, []string{"I", "U", "F"}, []string{"1", "2", "zz"}, &typedRow{})
+	if !errors.As(err, new(*strconv.NumError)) {
+		t.Errorf("UnmarshalRecord error %v does not wrap *strconv.NumError", err)
+	}
+}
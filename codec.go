@@ -0,0 +1,71 @@
+package csv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// cellCodecs lists the csv tag options that name a cell encoding, e.g. `csv:"data,base64,gzip"`.
+var cellCodecs = map[string]bool{"base64": true, "gzip": true}
+
+// tagCodecs returns the cell encodings named in a field's csv tag, in declaration order.
+func tagCodecs(tag reflect.StructTag) []string {
+	var codecs []string
+	for _, opt := range strings.Split(tag.Get("csv"), ",")[1:] {
+		if cellCodecs[opt] {
+			codecs = append(codecs, opt)
+		}
+	}
+	return codecs
+}
+
+// decodeCell applies the decoders in order, so `csv:"data,base64,gzip"` base64-decodes the cell and then gunzips it.
+func decodeCell(value string, codecs []string) (string, error) {
+	for _, codec := range codecs {
+		switch codec {
+		case "base64":
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return "", fmt.Errorf("base64: %w", err)
+			}
+			value = string(b)
+		case "gzip":
+			zr, err := gzip.NewReader(strings.NewReader(value))
+			if err != nil {
+				return "", fmt.Errorf("gzip: %w", err)
+			}
+			b, err := io.ReadAll(zr)
+			if err != nil {
+				return "", fmt.Errorf("gzip: %w", err)
+			}
+			value = string(b)
+		}
+	}
+	return value, nil
+}
+
+// encodeCell reverses decodeCell, applying the encoders in reverse order.
+func encodeCell(value string, codecs []string) (string, error) {
+	for i := len(codecs) - 1; i >= 0; i-- {
+		switch codecs[i] {
+		case "base64":
+			value = base64.StdEncoding.EncodeToString([]byte(value))
+		case "gzip":
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write([]byte(value)); err != nil {
+				return "", fmt.Errorf("gzip: %w", err)
+			}
+			if err := zw.Close(); err != nil {
+				return "", fmt.Errorf("gzip: %w", err)
+			}
+			value = buf.String()
+		}
+	}
+	return value, nil
+}
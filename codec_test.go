@@ -0,0 +1,43 @@
+package csv
+
+import (
+	"bytes"
+	"testing"
+)
+
+type blob struct {
+	ID   int
+	Data string `csv:"Data,base64,gzip"`
+	Raw  []byte `csv:"Raw,base64"`
+}
+
+func TestCellCodecsRoundTrip(t *testing.T) {
+	in := []*blob{{ID: 1, Data: "hello, world\nline two", Raw: []byte{0, 1, 2, 255}}, {ID: 2}}
+	content, err := MarshalCSV(nil, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ProcessCSV[blob](nil, content)
+	if err != nil {
+		t.Fatalf("ProcessCSV(%q): %v", content, err)
+	}
+	for i := range in {
+		if out[i].ID != in[i].ID || out[i].Data != in[i].Data || !bytes.Equal(out[i].Raw, in[i].Raw) {
+			t.Errorf("record %d = %+v, want %+v", i, *out[i], *in[i])
+		}
+	}
+}
+
+func TestCellCodecsDecodeErrors(t *testing.T) {
+	for _, content := range []string{
+		"ID,Data,Raw\n1,not base64!,\n",
+		"ID,Data,Raw\n1,aGVsbG8=,\n", // valid base64 that is not gzip
+	} {
+		if _, err := ProcessCSV[blob](nil, content); err == nil {
+			t.Errorf("ProcessCSV(%q): expected a decode error", content)
+		}
+		if _, err := ProcessCSV[blob](&Options{IgnoreFieldTypeErrors: true}, content); err != nil {
+			t.Errorf("ProcessCSV(%q): error not ignored with IgnoreFieldTypeErrors: %v", content, err)
+		}
+	}
+}
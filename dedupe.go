@@ -0,0 +1,101 @@
+package csv
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// deduper tracks the keys of records already seen so duplicates can be skipped.
+type deduper struct {
+	seen    map[string]struct{}
+	columns []int // columns are the indexes of the key columns; nil means the whole record is the key
+}
+
+// newDeduper returns a deduper keyed on the columns named by Options.DedupeKeyFields, or on the whole record when
+// none are named.
+func newDeduper(options *Options, headers []string) (*deduper, error) {
+	d := &deduper{seen: map[string]struct{}{}}
+	for _, name := range options.DedupeKeyFields {
+		column := -1
+		for i, header := range headers {
+			if header == name {
+				column = i
+				break
+			}
+		}
+		if column < 0 {
+			return nil, fmt.Errorf("dedupe key field %s not found in header", name)
+		}
+		d.columns = append(d.columns, column)
+	}
+	return d, nil
+}
+
+// duplicate reports whether a record with the same key was seen before and records the key otherwise.
+func (d *deduper) duplicate(record []string) bool {
+	var b strings.Builder
+	add := func(value string) {
+		b.WriteString(strconv.Itoa(len(value)))
+		b.WriteByte(':')
+		b.WriteString(value)
+	}
+	if d.columns == nil {
+		for _, value := range record {
+			add(value)
+		}
+	} else {
+		for _, column := range d.columns {
+			if column < len(record) {
+				add(record[column])
+			} else {
+				b.WriteByte('-')
+			}
+		}
+	}
+
+	key := b.String()
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+	d.seen[key] = struct{}{}
+	return false
+}
+
+// ProcessCSVWithHashes processes CSV input like ProcessCSV and also returns a hash of the raw record behind each
+// struct, at the same index, computed with Options.RowHashFunc or FNVRowHash when it is nil. Hashes depend only on
+// the record's fields, so they are stable across runs and files for external deduplication.
+func ProcessCSVWithHashes[T any](options *Options, content string) ([]*T, []string, error) {
+	hash := FNVRowHash
+	if options != nil && options.RowHashFunc != nil {
+		hash = options.RowHashFunc
+	}
+
+	var last string
+	var hashes []string
+	ts, err := processReader(options, strings.NewReader(content), processHooks[T]{
+		record: func(headers, record []string) {
+			last = hash(record)
+		},
+		onRecord: func(index int, v *T) {
+			hashes = append(hashes, last)
+		},
+	})
+	if _, collected := err.(RowErrors); err != nil && !collected {
+		return nil, nil, err
+	}
+	return ts, hashes, err
+}
+
+// FNVRowHash returns the 64-bit FNV-1a hash of record in hexadecimal. Each field is prefixed with its length so that
+// records such as ["a,b"] and ["a", "b"] hash differently.
+func FNVRowHash(record []string) string {
+	h := fnv.New64a()
+	for _, value := range record {
+		h.Write([]byte(strconv.Itoa(len(value))))
+		h.Write([]byte{':'})
+		h.Write([]byte(value))
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,72 @@
+package csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeduplicate(t *testing.T) {
+	content := "Level,Code,Comment\ninfo,1,a\ninfo,1,a\ninfo,1,b\nwarn,1,a\ninfo,2,a\n"
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{"full row", nil, []string{"info1a", "info1b", "warn1a", "info2a"}},
+		{"one key", []string{"Code"}, []string{"info1a", "info2a"}},
+		{"two keys", []string{"Level", "Code"}, []string{"info1a", "warn1a", "info2a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[logLine](&Options{Deduplicate: true, DedupeKeyFields: tt.keys}, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(got), len(tt.want))
+			}
+			for i, l := range got {
+				if s := l.Level + string(rune('0'+l.Code)) + l.Comment; s != tt.want[i] {
+					t.Errorf("row %d = %s, want %s", i, s, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeduplicateUnknownKey(t *testing.T) {
+	if _, err := ProcessCSV[logLine](&Options{Deduplicate: true, DedupeKeyFields: []string{"Missing"}}, "Level\ninfo\n"); err == nil {
+		t.Error("expected an error for a key field not in the header")
+	}
+}
+
+func TestProcessCSVWithHashes(t *testing.T) {
+	content := "A,B\na,b\nc,d\na,b\n"
+	_, first, err := ProcessCSVWithHashes[pair](nil, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second, err := ProcessCSVWithHashes[pair](nil, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("hashes changed between runs: %v and %v", first, second)
+	}
+	if len(first) != 3 || first[0] != "bdd1428ad635dedc" || first[0] != first[2] || first[0] == first[1] {
+		t.Errorf("got hashes %v, want equal hashes only for equal records", first)
+	}
+	if FNVRowHash([]string{"a,b"}) == FNVRowHash([]string{"a", "b"}) {
+		t.Error("records with different field boundaries hash equally")
+	}
+
+	options := &Options{RowHashFunc: func(record []string) string { return strings.Join(record, "|") }}
+	_, hashes, err := ProcessCSVWithHashes[pair](options, "A,B\na,b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hashes, []string{"a|b"}) {
+		t.Errorf("got hashes %v, want RowHashFunc used", hashes)
+	}
+}
@@ -0,0 +1,126 @@
+package csv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationFormat selects how time.Duration fields are parsed.
+type DurationFormat int
+
+const (
+	DurationFormatGo      DurationFormat = iota // DurationFormatGo parses durations with time.ParseDuration (e.g. "1h30m")
+	DurationFormatISO8601                       // DurationFormatISO8601 parses ISO 8601 durations (e.g. "PT1H30M")
+)
+
+// DurationUnit selects how time.Duration fields are marshalled.
+type DurationUnit int
+
+const (
+	DurationUnitString       DurationUnit = iota // DurationUnitString writes durations with time.Duration.String (e.g. "1h30m0s")
+	DurationUnitNanoseconds                      // DurationUnitNanoseconds writes durations as an integer number of nanoseconds
+	DurationUnitMilliseconds                     // DurationUnitMilliseconds writes durations as an integer number of milliseconds
+	DurationUnitSeconds                          // DurationUnitSeconds writes durations as an integer number of seconds
+)
+
+// size returns the length of one unit, or 0 for DurationUnitString.
+func (u DurationUnit) size() time.Duration {
+	switch u {
+	case DurationUnitNanoseconds:
+		return time.Nanosecond
+	case DurationUnitMilliseconds:
+		return time.Millisecond
+	case DurationUnitSeconds:
+		return time.Second
+	default:
+		return 0
+	}
+}
+
+// parseDuration parses s according to format. With an integer unit, a plain integer is read as a count of that unit,
+// so durations marshalled with the unit read back.
+func parseDuration(format DurationFormat, unit DurationUnit, s string) (time.Duration, error) {
+	if size := unit.size(); size != 0 {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Duration(n) * size, nil
+		}
+	}
+	if format == DurationFormatISO8601 {
+		return parseISO8601Duration(s)
+	}
+	return time.ParseDuration(s)
+}
+
+// formatDuration formats d according to unit. Integer units truncate toward zero.
+func formatDuration(unit DurationUnit, d time.Duration) string {
+	if size := unit.size(); size != 0 {
+		return strconv.FormatInt(int64(d/size), 10)
+	}
+	return d.String()
+}
+
+// parseISO8601Duration parses an ISO 8601 duration of the form PnWnDTnHnMnS, with an optional leading sign. Days are
+// 24 hours and weeks are 7 days. Years and months have no fixed length and are rejected.
+func parseISO8601Duration(s string) (time.Duration, error) {
+	in := s
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) == 1 {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", in)
+	}
+	s = s[1:]
+
+	var d float64
+	inTime := false
+	for len(s) > 0 {
+		if s[0] == 'T' {
+			if inTime || len(s) == 1 {
+				return 0, fmt.Errorf("invalid ISO 8601 duration %q", in)
+			}
+			inTime = true
+			s = s[1:]
+			continue
+		}
+
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' && r != ',' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", in)
+		}
+		n, err := strconv.ParseFloat(strings.Replace(s[:i], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", in)
+		}
+
+		var unit time.Duration
+		switch designator := s[i]; {
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && designator == 'D':
+			unit = 24 * time.Hour
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		case !inTime && (designator == 'Y' || designator == 'M'):
+			return 0, fmt.Errorf("unsupported ISO 8601 duration %q: years and months have no fixed length", in)
+		default:
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q", in)
+		}
+		d += n * float64(unit)
+		s = s[i+1:]
+	}
+
+	if neg {
+		d = -d
+	}
+	return time.Duration(d), nil
+}
@@ -0,0 +1,97 @@
+package csv
+
+import (
+	"testing"
+	"time"
+)
+
+type durations struct {
+	D time.Duration
+}
+
+func TestDurationFormatISO8601(t *testing.T) {
+	tests := []struct {
+		cell    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"PT15M", 15 * time.Minute, false},
+		{"P1DT2H", 26 * time.Hour, false},
+		{"PT1H30M", 90 * time.Minute, false},
+		{"PT0.5S", 500 * time.Millisecond, false},
+		{"-PT1M", -time.Minute, false},
+		{"P2W", 14 * 24 * time.Hour, false},
+		{"15m", 0, true},
+		{"PT", 0, true},
+		{"P1Y", 0, true},
+		{"PT1X", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cell, func(t *testing.T) {
+			got, err := ProcessCSV[durations](&Options{DurationFormat: DurationFormatISO8601}, "D\n"+tt.cell+"\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got[0].D)
+				}
+				got, err = ProcessCSV[durations](&Options{DurationFormat: DurationFormatISO8601, IgnoreFieldTypeErrors: true}, "D\n"+tt.cell+"\n")
+				if err != nil || got[0].D != 0 {
+					t.Errorf("with IgnoreFieldTypeErrors got %v, %v, want a zero duration", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].D != tt.want {
+				t.Errorf("got %v, want %v", got[0].D, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationFormatGo(t *testing.T) {
+	got, err := ProcessCSV[durations](nil, "D\n1h30m\n")
+	if err != nil || got[0].D != 90*time.Minute {
+		t.Errorf("got %v, %v, want 1h30m", got, err)
+	}
+	if _, err := ProcessCSV[durations](nil, "D\nPT15M\n"); err == nil {
+		t.Error("expected an error for an ISO 8601 duration in the Go format")
+	}
+}
+
+func TestMarshalCSVDurationUnit(t *testing.T) {
+	ts := []*durations{{90 * time.Minute}, {1500 * time.Millisecond}, {-2 * time.Second}}
+	tests := []struct {
+		name      string
+		unit      DurationUnit
+		want      string
+		roundTrip []time.Duration
+	}{
+		{"string", DurationUnitString, "D\n1h30m0s\n1.5s\n-2s\n", []time.Duration{90 * time.Minute, 1500 * time.Millisecond, -2 * time.Second}},
+		{"seconds", DurationUnitSeconds, "D\n5400\n1\n-2\n", []time.Duration{90 * time.Minute, time.Second, -2 * time.Second}},
+		{"milliseconds", DurationUnitMilliseconds, "D\n5400000\n1500\n-2000\n", []time.Duration{90 * time.Minute, 1500 * time.Millisecond, -2 * time.Second}},
+		{"nanoseconds", DurationUnitNanoseconds, "D\n5400000000000\n1500000000\n-2000000000\n", []time.Duration{90 * time.Minute, 1500 * time.Millisecond, -2 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &Options{DurationUnit: tt.unit}
+			out, err := MarshalCSV(options, ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Fatalf("got %q, want %q", out, tt.want)
+			}
+
+			got, err := ProcessCSV[durations](options, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.roundTrip {
+				if got[i].D != want {
+					t.Errorf("row %d read back as %v, want %v", i, got[i].D, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProcessFixedWidth processes fixed-width input and returns a slice of structs. Each line is sliced into fields of
+// the given widths, counted in characters. The first line is the header unless Options.HeaderOverride is set. Space
+// padding after each field is trimmed, as is leading space when TrimLeadingSpace is set. Blank lines and lines
+// starting with the Comment character are skipped.
+func ProcessFixedWidth[T any](options *Options, content string, widths []int) ([]*T, error) {
+	for _, w := range widths {
+		if w <= 0 {
+			return nil, fmt.Errorf("invalid column width %d", w)
+		}
+	}
+	options = normalizeOptions(options)
+	r := &fixedWidthReader{s: newLineScanner(strings.NewReader(content)), options: options, widths: widths}
+	return processRecords[T](options, r, processHooks[T]{})
+}
+
+type fixedWidthReader struct {
+	s       *bufio.Scanner
+	options *Options
+	widths  []int
+}
+
+func (r *fixedWidthReader) Read() ([]string, error) {
+	for r.s.Scan() {
+		line := strings.TrimSuffix(r.s.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if r.options.Comment != 0 && strings.HasPrefix(line, string(r.options.Comment)) {
+			continue
+		}
+
+		record := make([]string, len(r.widths))
+		for i, w := range r.widths {
+			n := 0
+			end := 0
+			for end < len(line) && n < w {
+				_, size := utf8.DecodeRuneInString(line[end:])
+				end += size
+				n++
+			}
+			field := strings.TrimRight(line[:end], " ")
+			if r.options.TrimLeadingSpace {
+				field = strings.TrimLeft(field, " ")
+			}
+			record[i] = field
+			line = line[end:]
+		}
+		return record, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,17 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcessFixedWidthLongLine(t *testing.T) {
+	long := strings.Repeat("x", 70*1024)
+	got, err := ProcessFixedWidth[pair](&Options{HeaderOverride: []string{"A", "B"}}, "a   "+long+"\n", []int{4, len(long)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].A != "a" || got[0].B != long {
+		t.Errorf("long line not read whole")
+	}
+}
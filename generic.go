@@ -0,0 +1,55 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ProcessCSVGeneric processes CSV input without a struct, returning the header and a map from header to cell for
+// each record. The reader options, such as Separator, StringSeparator, Comment, CommentPrefix and TrimLeadingSpace,
+// apply as in ProcessCSV, as do CommentHeaderOnly, HeaderOverride and HeaderRows; of the cell options, NullValues and
+// StripCR apply. A record shorter than the header, as FieldsPerRecord may allow, omits the missing columns, and a
+// header repeated in the header row keeps the value of its last column.
+func ProcessCSVGeneric(options *Options, content string) ([]string, []map[string]string, error) {
+	options = normalizeOptions(options)
+	r := newRecordReader(options, strings.NewReader(content))
+
+	headers, err := readHeader(options, r)
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading csv: %w", err)
+	}
+
+	rows := []map[string]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading csv: %w", err)
+		}
+		if len(record) > len(headers) {
+			return nil, nil, fmt.Errorf("error reading csv: row %d has %d fields but the header has %d", len(rows)+1, len(record), len(headers))
+		}
+
+		row := make(map[string]string, len(record))
+		for i, value := range record {
+			for _, null := range options.NullValues {
+				if value == null {
+					value = ""
+					break
+				}
+			}
+			if options.StripCR {
+				value = strings.TrimSuffix(value, "\r")
+			}
+			row[headers[i]] = value
+		}
+		rows = append(rows, row)
+	}
+	return headers, rows, nil
+}
@@ -0,0 +1,56 @@
+package csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessCSVGeneric(t *testing.T) {
+	options := &Options{Separator: ';', NullValues: []string{`\N`}, FieldsPerRecord: -1}
+	headers, rows, err := ProcessCSVGeneric(options, "id;name;note\n1;ann;\\N\n2;bob\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id", "name", "note"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("got headers %v, want %v", headers, want)
+	}
+	want := []map[string]string{
+		{"id": "1", "name": "ann", "note": ""},
+		{"id": "2", "name": "bob"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows %v, want %v", rows, want)
+	}
+}
+
+func TestProcessCSVGenericErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"wide row", "a,b\n1,2,3\n", "row 1 has 3 fields but the header has 2"},
+		{"bare quote", "a,b\n1,x\"y\n", "error reading csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ProcessCSVGeneric(&Options{FieldsPerRecord: -1}, tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessCSVGenericCommentHeaderOnly(t *testing.T) {
+	options := &Options{Comment: '#', CommentHeaderOnly: true}
+	_, rows, err := ProcessCSVGeneric(options, "# exported today\nid,name\n1,ann\n#2,bob\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{{"id": "1", "name": "ann"}, {"id": "#2", "name": "bob"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got rows %v, want %v", rows, want)
+	}
+}
@@ -0,0 +1,166 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// IncrementalDecoder decodes structs from CSV lines fed one at a time. The first complete record is used as the
+// header unless Options.HeaderOverride is set. Each record is unmarshalled and, with Validate, validated on its own;
+// the other options acting across records or on the header, listed in recordOptions, are not supported.
+type IncrementalDecoder[T any] struct {
+	options *Options
+	err     error // err is the error returned by WriteLine for unsupported options
+	headers []string
+	pending strings.Builder
+	open    bool           // open is whether a quoted field is still open at the end of the pending lines
+	mapping []FieldBinding // mapping is resolved once the header is known
+}
+
+// NewIncrementalDecoder returns a decoder that is fed with WriteLine.
+func NewIncrementalDecoder[T any](options *Options) *IncrementalDecoder[T] {
+	options = normalizeOptions(options)
+	d := &IncrementalDecoder[T]{options: options}
+	if name, ok := unsupportedOption(options, "Validate", "HeaderOverride"); ok {
+		d.err = fmt.Errorf("IncrementalDecoder does not support %s", name)
+	}
+	if len(options.HeaderOverride) > 0 {
+		d.headers = options.HeaderOverride
+	}
+	return d
+}
+
+// WriteLine adds a line, without its line terminator, to the decoder and returns any structs completed by it. Lines
+// are buffered while a quoted field is open, so quoted fields spanning multiple lines are decoded once the closing
+// quote arrives. Quotes are tracked as csv.Reader does, opening a field only at its start; with LazyQuotes, EscapeChar
+// or WhitespaceDelimited, where quotes may be literal, each line is decoded on its own. When the options set one that
+// IncrementalDecoder does not support, WriteLine decodes nothing and returns an error.
+func (d *IncrementalDecoder[T]) WriteLine(line string) ([]*T, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.pending.WriteString(line)
+	d.pending.WriteString("\n")
+	if d.tracksQuotes() {
+		d.open = scanQuotes(d.options, line, d.open)
+		if d.open {
+			return nil, nil
+		}
+	}
+
+	content := d.pending.String()
+	d.pending.Reset()
+
+	r := newRecordReader(d.options, strings.NewReader(content))
+	ts := []*T{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ts, fmt.Errorf("error reading csv: %w", err)
+		}
+
+		if d.headers == nil {
+			d.headers = record
+			continue
+		}
+		if d.mapping == nil || !sameHeaders(d.mapping, d.headers) {
+			d.mapping, err = NewFieldBindings[T](d.options, d.headers)
+			if err != nil {
+				return ts, fmt.Errorf("error unmarshalling record: %w", err)
+			}
+		}
+
+		t := new(T)
+		err = unmarshalMapped(d.options, d.mapping, record, quotedFields(r), reflect.ValueOf(t).Elem())
+		if err != nil {
+			return ts, fmt.Errorf("error unmarshalling record: %w", err)
+		}
+		if d.options.Validate {
+			if d.options.Validator == nil {
+				return ts, fmt.Errorf("validation enabled but no Validator set")
+			}
+			if err := d.options.Validator(t); err != nil {
+				return ts, fmt.Errorf("validation failed: %w", err)
+			}
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// Reset discards the header and any buffered lines so that the decoder can be fed a new input, whose first complete
+// record is read as its header unless Options.HeaderOverride is set. The resolved field bindings are kept and reused
+// when the new header matches the previous one. The options cannot be changed on reset.
+func (d *IncrementalDecoder[T]) Reset() {
+	d.headers = nil
+	if len(d.options.HeaderOverride) > 0 {
+		d.headers = d.options.HeaderOverride
+	}
+	d.pending.Reset()
+	d.open = false
+}
+
+// tracksQuotes reports whether quotes in the lines fed to d can open a field spanning lines.
+func (d *IncrementalDecoder[T]) tracksQuotes() bool {
+	return !d.options.LazyQuotes && d.options.EscapeChar == 0 && !d.options.WhitespaceDelimited
+}
+
+// sameHeaders reports whether mapping was resolved for headers.
+func sameHeaders(mapping []FieldBinding, headers []string) bool {
+	if len(mapping) != len(headers) {
+		return false
+	}
+	for i, b := range mapping {
+		if b.Header != headers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scanQuotes returns whether a quoted field is open at the end of line, given whether one was open at its start. As
+// in csv.Reader, a field is quoted only when it begins with a quote, after leading space with TrimLeadingSpace; a
+// doubled quote inside it is literal and a single quote closes it. Comment lines outside a quoted field are ignored.
+func scanQuotes(options *Options, line string, open bool) bool {
+	sep := fieldSeparator(options)
+	if !open && options.Comment != 0 && strings.HasPrefix(line, string(options.Comment)) {
+		return false
+	}
+
+	i := 0
+	for {
+		if open {
+			j := strings.IndexByte(line[i:], '"')
+			if j < 0 {
+				return true
+			}
+			i += j + 1
+			if i < len(line) && line[i] == '"' {
+				i++
+				continue
+			}
+			open = false
+		} else {
+			if options.TrimLeadingSpace {
+				i += len(line[i:]) - len(strings.TrimLeftFunc(line[i:], unicode.IsSpace))
+			}
+			if i < len(line) && line[i] == '"' {
+				open = true
+				i++
+				continue
+			}
+		}
+
+		j := strings.Index(line[i:], sep)
+		if j < 0 {
+			return false
+		}
+		i += j + len(sep)
+	}
+}
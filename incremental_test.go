@@ -0,0 +1,146 @@
+package csv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// feed writes lines to d in order and returns every struct completed along the way.
+func feed[T any](t *testing.T, d *IncrementalDecoder[T], lines ...string) []T {
+	t.Helper()
+	var got []T
+	for _, line := range lines {
+		ts, err := d.WriteLine(line)
+		if err != nil {
+			t.Fatalf("WriteLine(%q): %v", line, err)
+		}
+		for _, v := range ts {
+			got = append(got, *v)
+		}
+	}
+	return got
+}
+
+func TestIncrementalDecoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		lines   []string
+		want    []pair
+	}{
+		{"one line per record", nil, []string{"A,B", "a,b", "c,d"}, []pair{{"a", "b"}, {"c", "d"}}},
+		{"quoted field spanning lines", nil, []string{"A,B", `"a`, `x",b`, "c,d"}, []pair{{"a\nx", "b"}, {"c", "d"}}},
+		{"doubled quote in spanning field", nil, []string{"A,B", `"a""`, `""x",b`}, []pair{{"a\"\n\"x", "b"}}},
+		{"quote closing mid line", nil, []string{"A,B", `"a,b",c`}, []pair{{"a,b", "c"}}},
+		{"second field quoted", nil, []string{"A,B", `a,"b`, `c"`}, []pair{{"a", "b\nc"}}},
+		{"header override", &Options{HeaderOverride: []string{"A", "B"}}, []string{"a,b"}, []pair{{"a", "b"}}},
+		{"bare quote with LazyQuotes", &Options{LazyQuotes: true}, []string{"A,B", `x,5"`, "y,6"}, []pair{{"x", `5"`}, {"y", "6"}}},
+		{"quote with EscapeChar", &Options{EscapeChar: '\\'}, []string{"A,B", `"x,5`, `y\,z,6`}, []pair{{`"x`, "5"}, {"y,z", "6"}}},
+		{"quote in comment", &Options{Comment: '#'}, []string{"A,B", `# "`, "a,b"}, []pair{{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := feed(t, NewIncrementalDecoder[pair](tt.options), tt.lines...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncrementalDecoderPending(t *testing.T) {
+	d := NewIncrementalDecoder[pair](nil)
+	feed(t, d, "A,B")
+	ts, err := d.WriteLine(`"a`)
+	if err != nil || len(ts) != 0 {
+		t.Fatalf("got %d structs and %v while the quote is open, want none", len(ts), err)
+	}
+	if got := feed(t, d, `",b`); !reflect.DeepEqual(got, []pair{{"a\n", "b"}}) {
+		t.Errorf("got %+v after the quote closed", got)
+	}
+}
+
+func TestIncrementalDecoderReset(t *testing.T) {
+	d := NewIncrementalDecoder[pair](nil)
+	if got := feed(t, d, "A,B", "a,b", `"open`); !reflect.DeepEqual(got, []pair{{"a", "b"}}) {
+		t.Fatalf("got %+v from the first input", got)
+	}
+	mapping := d.mapping
+
+	d.Reset()
+	if got := feed(t, d, "A,B", "c,d"); !reflect.DeepEqual(got, []pair{{"c", "d"}}) {
+		t.Errorf("got %+v from the second input", got)
+	}
+	if &d.mapping[0] != &mapping[0] {
+		t.Error("bindings were resolved again for a matching header")
+	}
+
+	d.Reset()
+	if got := feed(t, d, "B,A", "e,f"); !reflect.DeepEqual(got, []pair{{"f", "e"}}) {
+		t.Errorf("got %+v from an input with a different header", got)
+	}
+}
+
+func TestIncrementalDecoderResetHeaderOverride(t *testing.T) {
+	d := NewIncrementalDecoder[pair](&Options{HeaderOverride: []string{"A", "B"}})
+	feed(t, d, "a,b")
+	d.Reset()
+	if got := feed(t, d, "c,d"); !reflect.DeepEqual(got, []pair{{"c", "d"}}) {
+		t.Errorf("got %+v after reset", got)
+	}
+}
+
+func TestIncrementalDecoderValidate(t *testing.T) {
+	options := &Options{
+		Validate: true,
+		Validator: func(v interface{}) error {
+			if v.(*pair).B == "" {
+				return errors.New("B is required")
+			}
+			return nil
+		},
+	}
+	d := NewIncrementalDecoder[pair](options)
+	if got := feed(t, d, "A,B", "a,b"); !reflect.DeepEqual(got, []pair{{"a", "b"}}) {
+		t.Errorf("got %+v from a valid row", got)
+	}
+	if ts, err := d.WriteLine("c,"); err == nil || len(ts) != 0 {
+		t.Errorf("got %d structs and %v from an invalid row, want a validation error", len(ts), err)
+	}
+}
+
+func TestIncrementalDecoderUnsupportedOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+	}{
+		{"Deduplicate", &Options{Deduplicate: true}},
+		{"StopFunc", &Options{StopFunc: func(headers, record []string) bool { return false }}},
+		{"InferAndEnforceTypes", &Options{InferAndEnforceTypes: true}},
+		{"MinFields", &Options{MinFields: 1}},
+		{"MaxFields", &Options{MaxFields: 5}},
+		{"CollectErrors", &Options{CollectErrors: true}},
+		{"EnforceHeaderWidth", &Options{EnforceHeaderWidth: true}},
+		{"JoinTrailingInto", &Options{JoinTrailingInto: "B"}},
+		{"StrictHeaderMatch", &Options{StrictHeaderMatch: true}},
+		{"KeyValueMode", &Options{KeyValueMode: true}},
+		{"HeaderRows", &Options{HeaderRows: 2}},
+		{"CommentHeaderOnly", &Options{Comment: '#', CommentHeaderOnly: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewIncrementalDecoder[pair](tt.options)
+			for _, line := range []string{"A,B", "a,b"} {
+				ts, err := d.WriteLine(line)
+				if err == nil || !strings.Contains(err.Error(), tt.name) {
+					t.Errorf("WriteLine(%q) = %v, want %s to be rejected", line, err, tt.name)
+				}
+				if len(ts) != 0 {
+					t.Errorf("WriteLine(%q) decoded %d structs", line, len(ts))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package csv
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// cellKind is the kind of value inferred from a raw cell.
+type cellKind int
+
+const (
+	kindUnknown cellKind = iota // kindUnknown is the kind of an empty cell
+	kindBool                    // kindBool is "true" or "false" as accepted by strconv.ParseBool, excluding "t" and "f"
+	kindInt                     // kindInt is a base 10 integer
+	kindFloat                   // kindFloat is a decimal number that is not an integer
+	kindTime                    // kindTime is an RFC3339 timestamp or a date such as 2006-01-02
+	kindString                  // kindString is any other text
+)
+
+func (k cellKind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int"
+	case kindFloat:
+		return "float"
+	case kindTime:
+		return "time"
+	case kindString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// inferKind returns the kind of value.
+func inferKind(value string) cellKind {
+	if value == "" {
+		return kindUnknown
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return kindInt
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return kindFloat
+	}
+	if _, err := strconv.ParseBool(value); err == nil && len(value) > 1 {
+		return kindBool
+	}
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return kindTime
+	}
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		return kindTime
+	}
+	return kindString
+}
+
+// typeEnforcer infers the kind of each column from the first records and reports records that drift from it. A
+// column's kind is fixed by its first non-empty cell, except that an int column widens to float. Empty cells never
+// drift.
+type typeEnforcer struct {
+	kinds []cellKind
+}
+
+// check reports the first cell of record whose kind does not match its column.
+func (e *typeEnforcer) check(headers, record []string) error {
+	for len(e.kinds) < len(record) {
+		e.kinds = append(e.kinds, kindUnknown)
+	}
+	for i, value := range record {
+		k := inferKind(value)
+		switch cur := e.kinds[i]; {
+		case k == kindUnknown || k == cur:
+		case cur == kindUnknown:
+			e.kinds[i] = k
+		case cur == kindInt && k == kindFloat:
+			e.kinds[i] = kindFloat
+		case cur == kindFloat && k == kindInt:
+		default:
+			header := strconv.Itoa(i)
+			if i < len(headers) {
+				header = headers[i]
+			}
+			return fmt.Errorf("column %s inferred as %s has %s value %q", header, cur, k, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	ID    string
+	Score string
+	Seen  string
+}
+
+func TestInferAndEnforceTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"consistent", "ID,Score,Seen\n1,2,2024-01-02\n2,3,2024-01-03\n", ""},
+		{"int widens to float", "ID,Score,Seen\n1,2,2024-01-02\n2,3.5,2024-01-03\n3,4,2024-01-04\n", ""},
+		{"empty cells", "ID,Score,Seen\n1,,\n2,3,2024-01-03\n3,,\n", ""},
+		{"text in numeric column", "ID,Score,Seen\n1,2,2024-01-02\n2,3,2024-01-03\n3,n/a,2024-01-04\n", "row 3: type drift: column Score inferred as int has string value \"n/a\""},
+		{"number in time column", "ID,Score,Seen\n1,2,2024-01-02\n2,3,7\n", "row 2: type drift: column Seen inferred as time has int value \"7\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[sample](&Options{InferAndEnforceTypes: true}, tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != strings.Count(tt.content, "\n")-1 {
+				t.Errorf("got %d rows", len(got))
+			}
+		})
+	}
+
+	if _, err := ProcessCSV[sample](nil, "ID,Score,Seen\n1,2,x\n2,n/a,y\n"); err != nil {
+		t.Errorf("drift rejected without InferAndEnforceTypes: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// WriteJSONL writes records to w as JSON lines for debugging, one object per struct. The keys are the headers
+// MarshalCSV would write, in the same order, and the values are the field values encoded with encoding/json. Nil
+// structs are skipped.
+func WriteJSONL[T any](options *Options, w io.Writer, records []*T) error {
+	e, err := newEncoder[T](options, io.Discard)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, t := range records {
+		if t == nil {
+			continue
+		}
+		s := reflect.ValueOf(t).Elem()
+		buf.Reset()
+		buf.WriteByte('{')
+		for j, index := range e.fields {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(e.headers[j])
+			value, err := json.Marshal(s.FieldByIndex(index).Interface())
+			if err != nil {
+				return fmt.Errorf("error marshalling field %s: %w", e.headers[j], err)
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteString("}\n")
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("error writing json: %w", err)
+		}
+	}
+	return nil
+}
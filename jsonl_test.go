@@ -0,0 +1,42 @@
+package csv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteJSONL(t *testing.T) {
+	in := []*tagged{{Name: "Ada", Email: "ada@example.com"}, nil, {Name: `Grace "Amazing"`, Email: "grace@example.com"}}
+	tests := []struct {
+		name    string
+		options *Options
+		want    string
+	}{
+		{"field names", nil, "{\"Name\":\"Ada\",\"Email\":\"ada@example.com\"}\n{\"Name\":\"Grace \\\"Amazing\\\"\",\"Email\":\"grace@example.com\"}\n"},
+		{"struct tags", &Options{UseStructTags: true}, "{\"full_name\":\"Ada\",\"email\":\"ada@example.com\"}\n{\"full_name\":\"Grace \\\"Amazing\\\"\",\"email\":\"grace@example.com\"}\n"},
+		{"selected fields", &Options{MarshalFields: []string{"Email"}}, "{\"Email\":\"ada@example.com\"}\n{\"Email\":\"grace@example.com\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteJSONL(tt.options, &buf, in); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSONLNested(t *testing.T) {
+	in := []*contact{{Name: "Ada", Tags: []string{"math"}, Address: address{"12 St James's Sq", "London"}}}
+	var buf bytes.Buffer
+	if err := WriteJSONL(nil, &buf, in); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"Name\":\"Ada\",\"Tags\":[\"math\"],\"Address.Street\":\"12 St James's Sq\",\"Address.City\":\"London\"}\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
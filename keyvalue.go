@@ -0,0 +1,94 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// processKeyValueRecords reads records of key=value fields from r and unmarshals them, binding each value to the
+// field its key resolves to. There is no header row. Fields may appear in any order and may be omitted; empty fields
+// are skipped, and a key repeated within a record binds like a repeated header, so its last value wins. Bindings are
+// cached per distinct key sequence. StopFunc, Validate, CollectErrors and the hooks apply as in processRecords,
+// receiving the keys of each record as its headers; a field that is not a pair or a key that does not resolve is a
+// row error. With CommentHeaderOnly, comment lines are skipped only before the first record. The other options
+// acting across records or on the header, listed in recordOptions, are an error when set. options must already be
+// normalized.
+func processKeyValueRecords[T any](options *Options, r recordReader, hooks processHooks[T]) ([]*T, error) {
+	if name, ok := unsupportedOption(options, "KeyValueMode", "StopFunc", "Validate", "CollectErrors", "CommentHeaderOnly"); ok {
+		return nil, fmt.Errorf("KeyValueMode does not support %s", name)
+	}
+
+	sink := newRecordSink(options, hooks)
+	mappings := map[string][]FieldBinding{}
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		sink.row++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && sink.collect(parseErr.StartLine, err) {
+				continue
+			}
+			return nil, fmt.Errorf("error reading csv: %w", err)
+		}
+		if sink.ts == nil {
+			sink.ts = []*T{}
+		}
+
+		headers, record, err := splitKeyValues(fields)
+		if err != nil {
+			if sink.collect(0, err) {
+				continue
+			}
+			return nil, fmt.Errorf("error reading csv: row %d: %w", sink.row, err)
+		}
+
+		if options.StopFunc != nil && options.StopFunc(headers, record) {
+			break
+		}
+		if hooks.record != nil {
+			hooks.record(headers, record)
+		}
+
+		key := strings.Join(headers, "\x00")
+		mapping, ok := mappings[key]
+		if !ok {
+			mapping, err = NewFieldBindings[T](options, headers)
+			if err != nil {
+				if sink.collect(0, err) {
+					continue
+				}
+				return nil, fmt.Errorf("error unmarshalling record: row %d: %w", sink.row, err)
+			}
+			mappings[key] = mapping
+		}
+
+		if err := sink.add(mapping, record, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sink.result()
+}
+
+// splitKeyValues splits each non-empty field of a key=value record into its key and value.
+func splitKeyValues(fields []string) (keys, values []string, err error) {
+	keys = make([]string, 0, len(fields))
+	values = make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("field %q is not a key=value pair", field)
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, nil
+}
@@ -0,0 +1,141 @@
+package csv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type person struct {
+	Name string
+	Age  int
+	City string
+}
+
+func TestKeyValueMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []person
+		wantErr bool
+	}{
+		{"reordered", "Name=Alice,Age=30,City=Oslo\nAge=41,City=Rome,Name=Bob\n", []person{{"Alice", 30, "Oslo"}, {"Bob", 41, "Rome"}}, false},
+		{"omitted fields", "Name=Alice\nCity=Rome,Age=5\n", []person{{Name: "Alice"}, {Age: 5, City: "Rome"}}, false},
+		{"value with equals", "Name=a=b,Age=1\n", []person{{Name: "a=b", Age: 1}}, false},
+		{"repeated key", "Name=Alice,Name=Alicia,Age=30\n", []person{{Name: "Alicia", Age: 30}}, false},
+		{"not a pair", "Name=Alice,30\n", nil, true},
+		{"unknown key", "Name=Alice,Job=x\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[person](&Options{KeyValueMode: true}, tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows []person
+			for _, p := range got {
+				rows = append(rows, *p)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("got %+v, want %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyValueModeCollectErrors(t *testing.T) {
+	content := "Name=Alice,Age=30\nName=Bob,30\nName=Carol,Job=x\nName=Dan,Age=x\nName=Eve,Age=5\n"
+	got, err := ProcessCSV[person](&Options{KeyValueMode: true, CollectErrors: true}, content)
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("got error %v, want RowErrors", err)
+	}
+	var rows []int
+	for _, e := range rowErrs {
+		rows = append(rows, e.Row)
+	}
+	if !reflect.DeepEqual(rows, []int{2, 3, 4}) {
+		t.Errorf("got row errors for rows %v, want [2 3 4]", rows)
+	}
+	var names []string
+	for _, p := range got {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Alice", "Eve"}) {
+		t.Errorf("got rows %v, want [Alice Eve]", names)
+	}
+}
+
+func TestKeyValueModeStream(t *testing.T) {
+	content := "Name=Alice,Age=30\nName=Bob,30\nName=Eve,Age=5\n"
+	ts, errs := drain(ProcessCSVStream[person](&Options{KeyValueMode: true}, strings.NewReader(content)))
+	if len(ts) != 2 || ts[0].Name != "Alice" || ts[1].Name != "Eve" {
+		t.Errorf("got %d structs, want Alice and Eve", len(ts))
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want one", errs)
+	}
+	var rowErr *RowError
+	if !errors.As(errs[0], &rowErr) || rowErr.Row != 2 {
+		t.Errorf("got %v, want a *RowError for row 2", errs[0])
+	}
+}
+
+func TestKeyValueModeUnsupportedOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+	}{
+		{"Deduplicate", &Options{KeyValueMode: true, Deduplicate: true}},
+		{"InferAndEnforceTypes", &Options{KeyValueMode: true, InferAndEnforceTypes: true}},
+		{"MinFields", &Options{KeyValueMode: true, MinFields: 1}},
+		{"MaxFields", &Options{KeyValueMode: true, MaxFields: 5}},
+		{"EnforceHeaderWidth", &Options{KeyValueMode: true, EnforceHeaderWidth: true}},
+		{"JoinTrailingInto", &Options{KeyValueMode: true, JoinTrailingInto: "City"}},
+		{"StrictHeaderMatch", &Options{KeyValueMode: true, StrictHeaderMatch: true}},
+		{"HeaderRows", &Options{KeyValueMode: true, HeaderRows: 2}},
+		{"HeaderOverride", &Options{KeyValueMode: true, HeaderOverride: []string{"Name"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProcessCSV[person](tt.options, "Name=Alice\n")
+			if err == nil || !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("got %v, want %s to be rejected", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestKeyValueModeCommentHeaderOnly(t *testing.T) {
+	content := "# exported today\nName=Alice\n#note=x,Name=Bob\n"
+	tests := []struct {
+		name    string
+		options *Options
+		want    []string
+	}{
+		{"comments everywhere", &Options{KeyValueMode: true, IgnoreUnknownFields: true, Comment: '#'}, []string{"Alice"}},
+		{"comments before the first record", &Options{KeyValueMode: true, IgnoreUnknownFields: true, Comment: '#', CommentHeaderOnly: true}, []string{"Alice", "Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[person](tt.options, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, p := range got {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
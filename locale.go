@@ -0,0 +1,106 @@
+package csv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// numberFormat holds the separators a locale uses for numbers.
+type numberFormat struct {
+	group   string
+	decimal string
+}
+
+var numberFormats sync.Map // map[string]numberFormat keyed by locale tag
+
+// localeNumberFormat returns the grouping and decimal separators of the BCP 47 locale tag, found by formatting a
+// reference number with a golang.org/x/text/message printer.
+func localeNumberFormat(tag string) (numberFormat, error) {
+	if nf, ok := numberFormats.Load(tag); ok {
+		return nf.(numberFormat), nil
+	}
+
+	t, err := language.Parse(tag)
+	if err != nil {
+		return numberFormat{}, fmt.Errorf("invalid locale %q: %w", tag, err)
+	}
+	s := message.NewPrinter(t).Sprintf("%.1f", 1234.5)
+	i := strings.Index(s, "234")
+	if !strings.HasPrefix(s, "1") || i < 0 || !strings.HasSuffix(s, "5") {
+		return numberFormat{}, fmt.Errorf("unsupported number format %q for locale %q", s, tag)
+	}
+	nf := numberFormat{group: s[1:i], decimal: s[i+3 : len(s)-1]}
+	numberFormats.Store(tag, nf)
+	return nf, nil
+}
+
+// delocalizeNumber rewrites a number formatted for the locale tag into the form understood by strconv, removing
+// grouping separators and replacing the decimal separator with ".". When the locale groups with a space, any kind of
+// space is accepted as a separator.
+func delocalizeNumber(tag, s string) (string, error) {
+	nf, err := localeNumberFormat(tag)
+	if err != nil {
+		return s, err
+	}
+
+	if nf.group != "" {
+		if r := []rune(nf.group); len(r) == 1 && unicode.IsSpace(r[0]) {
+			s = strings.Map(func(r rune) rune {
+				if unicode.IsSpace(r) {
+					return -1
+				}
+				return r
+			}, s)
+		} else {
+			s = strings.ReplaceAll(s, nf.group, "")
+		}
+	}
+	if nf.decimal != "." {
+		s = strings.Replace(s, nf.decimal, ".", 1)
+	}
+	return s, nil
+}
+
+// stripCurrency removes the currency symbols and surrounding space at either end of s, keeping a leading sign. When
+// symbols is empty, any rune in the Unicode currency symbol category is removed.
+func stripCurrency(symbols []string, s string) string {
+	trim := func(s string) string {
+		for {
+			t := strings.TrimSpace(s)
+			if len(symbols) == 0 {
+				t = strings.TrimFunc(t, func(r rune) bool { return unicode.Is(unicode.Sc, r) })
+			}
+			for _, symbol := range symbols {
+				t = strings.TrimSuffix(strings.TrimPrefix(t, symbol), symbol)
+			}
+			if t == s {
+				return s
+			}
+			s = t
+		}
+	}
+
+	s = trim(s)
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		return s[:1] + trim(s[1:])
+	}
+	return s
+}
+
+// accountingNumber rewrites a number written in parentheses, as accounting exports write negatives, with a leading
+// minus sign, keeping any currency symbol inside the parentheses. With ungroup, "," grouping separators are removed.
+func accountingNumber(s string, ungroup bool) string {
+	t := strings.TrimSpace(s)
+	if len(t) > 2 && strings.HasPrefix(t, "(") && strings.HasSuffix(t, ")") {
+		s = "-" + strings.TrimSpace(t[1:len(t)-1])
+	}
+	if ungroup {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package csv
+
+import "testing"
+
+type money struct {
+	Amount float64
+	Units  int
+}
+
+func TestCurrencyStrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		content string
+		want    money
+	}{
+		{"dollar", Options{CurrencyStrip: true, Locale: "en-US"}, "Amount,Units\n\"$1,234.56\",$7\n", money{1234.56, 7}},
+		{"euro suffix", Options{CurrencyStrip: true, Locale: "de-DE"}, "Amount,Units\n\"1.234,56 €\",7 €\n", money{1234.56, 7}},
+		{"euro prefix", Options{CurrencyStrip: true, Locale: "de-DE"}, "Amount,Units\n\"€1.234,56\",€7\n", money{1234.56, 7}},
+		{"negative", Options{CurrencyStrip: true, Locale: "en-US"}, "Amount,Units\n\"-$1,234.56\",- $7\n", money{-1234.56, -7}},
+		{"symbol set", Options{CurrencyStrip: true, CurrencySymbols: []string{"USD"}}, "Amount,Units\n12.5 USD,USD 7\n", money{12.5, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[money](&tt.options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got[0] != tt.want {
+				t.Errorf("got %+v, want %+v", *got[0], tt.want)
+			}
+		})
+	}
+
+	if _, err := ProcessCSV[money](&Options{CurrencyStrip: true, CurrencySymbols: []string{"USD"}}, "Amount,Units\n$12.5,7\n"); err == nil {
+		t.Error("expected an error for a symbol outside CurrencySymbols")
+	}
+}
+
+type ledger struct {
+	Balance int
+	Amount  float64
+}
+
+func TestAccountingNegatives(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		row     string
+		want    ledger
+		wantErr bool
+	}{
+		{"parenthesized", Options{AccountingNegatives: true}, `"(1,234)","(1,234.5)"`, ledger{-1234, -1234.5}, false},
+		{"grouped", Options{AccountingNegatives: true}, `"1,234","1,234.5"`, ledger{1234, 1234.5}, false},
+		{"plain", Options{AccountingNegatives: true}, "(12),-3.5", ledger{-12, -3.5}, false},
+		{"spaces", Options{AccountingNegatives: true}, "( 12 ), (3) ", ledger{-12, -3}, false},
+		{"currency", Options{AccountingNegatives: true, CurrencyStrip: true}, `"($1,234)","$5"`, ledger{-1234, 5}, false},
+		{"locale", Options{AccountingNegatives: true, Locale: "de-DE"}, `"(1.234)","(1.234,5)"`, ledger{-1234, -1234.5}, false},
+		{"disabled", Options{}, "(12),1", ledger{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[ledger](&tt.options, "Balance,Amount\n"+tt.row+"\n")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", *got[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got[0] != tt.want {
+				t.Errorf("got %+v, want %+v", *got[0], tt.want)
+			}
+		})
+	}
+}
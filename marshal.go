@@ -0,0 +1,493 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarshalCSV marshals a slice of structs into CSV content. The output can be read back with ProcessCSV using the same
+// options. Nested structs are flattened into dotted headers (e.g. "Address.City") and slice elements are joined with
+// Options.SliceDelimiter. Fields whose csv tag has the string option, as in `csv:"id,string"`, are always quoted.
+// Strings holding "\r\n" are not read back as written, since csv.Reader reads it inside a quoted field as "\n".
+func MarshalCSV[T any](options *Options, ts []*T) (string, error) {
+	var buf bytes.Buffer
+	if err := WriteCSV(options, &buf, ts); err != nil {
+		return "", err
+	}
+	if options != nil && options.TrailingNewline != nil && !*options.TrailingNewline {
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+	return buf.String(), nil
+}
+
+// WriteCSV marshals a slice of structs as CSV to w, like MarshalCSV. The csv.Writer is always flushed, and when w has
+// a Flush method, such as *bufio.Writer or *gzip.Writer, it is flushed too so that all output reaches the underlying
+// writer. WriteCSV never closes w; callers of writers that must be closed to complete their output, such as
+// *gzip.Writer, must still call Close.
+func WriteCSV[T any](options *Options, w io.Writer, ts []*T) error {
+	e, err := newEncoder[T](options, w)
+	if err != nil {
+		return err
+	}
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		if err := e.write(t); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// WriteCSVFile marshals a slice of structs as CSV to the named file, creating or truncating it.
+func WriteCSVFile[T any](options *Options, name string, ts []*T) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	if err := WriteCSV(options, f, ts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+	return nil
+}
+
+// WriteCSVChan marshals structs received from ch as CSV to w until ch is closed, flushing like WriteCSV. The header is
+// written when the first value arrives; if ch closes without a value, nothing is written unless HeaderOnEmpty is set.
+// On error WriteCSVChan returns without draining ch.
+func WriteCSVChan[T any](options *Options, w io.Writer, ch <-chan *T) error {
+	e, err := newEncoder[T](options, w)
+	if err != nil {
+		return err
+	}
+	header := false
+	for t := range ch {
+		if !header {
+			if err := e.writeHeader(); err != nil {
+				return err
+			}
+			header = true
+		}
+		if err := e.write(t); err != nil {
+			return err
+		}
+	}
+	if !header && e.options.HeaderOnEmpty {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// encoder writes structs of type T as CSV records.
+type encoder[T any] struct {
+	options *Options
+	out     io.Writer
+	w       *csv.Writer
+	rt      reflect.Type
+	headers []string
+	fields  [][]int
+	quoted  []bool // quoted marks the columns whose csv tag has the string option; nil when there are none
+	written int    // written is the number of records written, for FlushEvery
+}
+
+func newEncoder[T any](options *Options, out io.Writer) (*encoder[T], error) {
+	if options == nil {
+		options = &Options{}
+	}
+	useStructTags := options.UseStructTags && !options.UseFieldNames
+
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %s", rt.Kind())
+	}
+
+	headers, fields := marshalColumns(rt, "", useStructTags)
+	if len(options.MarshalFields) > 0 {
+		var err error
+		headers, fields, err = selectColumns(headers, fields, options.MarshalFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := csv.NewWriter(out)
+	if options.Separator != 0 {
+		w.Comma = options.Separator
+	}
+	e := &encoder[T]{options: options, out: out, w: w, rt: rt, headers: headers, fields: fields}
+	for j, index := range fields {
+		if _, ok := tagOptions(rt.FieldByIndex(index).Tag)["string"]; ok {
+			if e.quoted == nil {
+				e.quoted = make([]bool, len(fields))
+			}
+			e.quoted[j] = true
+		}
+	}
+	return e, nil
+}
+
+// writeHeader writes the header record, preceded by a UTF-8 byte order mark when WriteBOM is set.
+func (e *encoder[T]) writeHeader() error {
+	if e.options.WriteBOM {
+		if _, err := io.WriteString(e.out, "\uFEFF"); err != nil {
+			return fmt.Errorf("error writing csv: %w", err)
+		}
+	}
+	if err := writeRecord(e.w, e.out, e.headers); err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+	return nil
+}
+
+// write writes one struct as a record, flushing after every FlushEvery records. Nil structs are skipped.
+func (e *encoder[T]) write(t *T) error {
+	if t == nil {
+		return nil
+	}
+	s := reflect.ValueOf(t).Elem()
+	record := make([]string, len(e.fields))
+	for j, index := range e.fields {
+		f := s.FieldByIndex(index)
+		if e.options.ZeroAsEmpty && f.Kind() != reflect.Ptr && f.IsZero() {
+			continue
+		}
+		value, err := marshalField(e.options, f)
+		if err != nil {
+			return fmt.Errorf("error marshalling field %s: %w", e.headers[j], err)
+		}
+		if codecs := tagCodecs(e.rt.FieldByIndex(index).Tag); len(codecs) > 0 {
+			value, err = encodeCell(value, codecs)
+			if err != nil {
+				return fmt.Errorf("error marshalling field %s: %w", e.headers[j], err)
+			}
+		}
+		record[j] = value
+	}
+	var err error
+	if e.quoted != nil {
+		err = e.writeQuoted(record)
+	} else {
+		err = writeRecord(e.w, e.out, record)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+
+	e.written++
+	if n := e.options.FlushEvery; n > 0 && e.written%n == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// writeQuoted writes record directly to the output, always quoting the columns marked in quoted. Other fields are
+// quoted only when required, as by csv.Writer.
+func (e *encoder[T]) writeQuoted(record []string) error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+
+	sep := string(e.w.Comma)
+	var b strings.Builder
+	for j, value := range record {
+		if j > 0 {
+			b.WriteString(sep)
+		}
+		if e.quoted[j] || fieldNeedsQuotes(value, sep) {
+			b.WriteByte('"')
+			b.WriteString(strings.ReplaceAll(value, `"`, `""`))
+			b.WriteByte('"')
+		} else {
+			b.WriteString(value)
+		}
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(e.out, b.String())
+	return err
+}
+
+// fieldNeedsQuotes reports whether csv.Writer would quote value.
+func fieldNeedsQuotes(value, sep string) bool {
+	if value == "" {
+		return false
+	}
+	if value == `\.` || strings.Contains(value, sep) || strings.ContainsAny(value, "\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return unicode.IsSpace(r)
+}
+
+// flush flushes the csv.Writer and then the output writer when it has a Flush method.
+func (e *encoder[T]) flush() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return fmt.Errorf("error writing csv: %w", err)
+	}
+	if f, ok := e.out.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("error flushing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// MarshalCSVMap marshals a map of structs into CSV content, emitting rows in key order. Keys of integer, float and
+// string kinds are sorted by value; all other keys are sorted by their fmt.Sprint representation.
+func MarshalCSVMap[K comparable, T any](options *Options, m map[K]*T) (string, error) {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return lessKey(reflect.ValueOf(keys[i]), reflect.ValueOf(keys[j]))
+	})
+
+	ts := make([]*T, 0, len(keys))
+	for _, k := range keys {
+		ts = append(ts, m[k])
+	}
+	return MarshalCSV(options, ts)
+}
+
+func floatFormat(options *Options) byte {
+	if options.FloatFormat == 0 {
+		return 'g'
+	}
+	return options.FloatFormat
+}
+
+func lessKey(a, b reflect.Value) bool {
+	if a.Kind() != b.Kind() {
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+// marshalColumns returns the headers of the exported fields of rt and the index path of each field, flattening
+// nested structs into headers prefixed with their parent's name.
+func marshalColumns(rt reflect.Type, prefix string, useStructTags bool) ([]string, [][]int) {
+	var headers []string
+	var fields [][]int
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if _, ok := tagOptions(f.Tag)["rownum"]; !f.IsExported() || ok {
+			continue
+		}
+		name := f.Name
+		if useStructTags {
+			name = strings.Split(f.Tag.Get("csv"), ",")[0] // use split to ignore tag "options" like omitempty, etc.
+			if name == "" {
+				continue
+			}
+		}
+
+		if isNestedStruct(f.Type) {
+			nestedHeaders, nestedFields := marshalColumns(f.Type, prefix+name+".", useStructTags)
+			headers = append(headers, nestedHeaders...)
+			for _, index := range nestedFields {
+				fields = append(fields, append([]int{i}, index...))
+			}
+			continue
+		}
+		headers = append(headers, prefix+name)
+		fields = append(fields, []int{i})
+	}
+	return headers, fields
+}
+
+// selectColumns keeps only the columns named in names, in that order.
+func selectColumns(headers []string, fields [][]int, names []string) ([]string, [][]int, error) {
+	selectedFields := make([][]int, 0, len(names))
+	for _, name := range names {
+		found := false
+		for i, header := range headers {
+			if header == name {
+				selectedFields = append(selectedFields, fields[i])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown marshal field: %s", name)
+		}
+	}
+	return names, selectedFields, nil
+}
+
+// writeRecord writes a single record. A record made of one empty field is written as a quoted empty string because
+// csv.Writer would otherwise emit a blank line, which csv.Reader skips.
+func writeRecord(w *csv.Writer, out io.Writer, record []string) error {
+	if len(record) == 1 && record[0] == "" {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		_, err := io.WriteString(out, "\"\"\n")
+		return err
+	}
+	return w.Write(record)
+}
+
+// marshalField formats a single field value. Nil pointers and empty strings are written as Options.WriteNullAs, while
+// a non-nil pointer is always written as the value it points to, even when that is the zero value. An interface is
+// written as its dynamic value. Named types not handled by name are written by kind, integers listed in
+// Options.EnumMap as their label. Empty and nil slice, array and map elements are written as the empty string, since
+// WriteNullAs applies to whole fields only.
+func marshalField(options *Options, f reflect.Value) (string, error) {
+	return marshalValue(options, f, options.WriteNullAs)
+}
+
+// marshalValue formats f as marshalField does, writing null for a nil pointer, nil interface or empty string.
+func marshalValue(options *Options, f reflect.Value, null string) (string, error) {
+	ptr := f.Kind() == reflect.Ptr
+	if ptr {
+		if f.IsNil() {
+			return null, nil
+		}
+		f = f.Elem()
+	}
+
+	if f.Kind() == reflect.Interface {
+		if f.IsNil() {
+			return null, nil
+		}
+		return marshalValue(options, f.Elem(), null)
+	}
+
+	if (f.Kind() == reflect.Slice || f.Kind() == reflect.Array) && f.Type().Elem().Kind() != reflect.Uint8 {
+		parts := make([]string, f.Len())
+		for i := range parts {
+			part, err := marshalValue(options, f.Index(i), "")
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		return strings.Join(parts, sliceDelimiter(options)), nil
+	}
+
+	if f.Kind() == reflect.Map {
+		keys := f.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			key, err := marshalValue(options, k, "")
+			if err != nil {
+				return "", err
+			}
+			elem, err := marshalValue(options, f.MapIndex(k), "")
+			if err != nil {
+				return "", err
+			}
+			parts[i] = key + mapKeySeparator(options) + elem
+		}
+		return strings.Join(parts, sliceDelimiter(options)), nil
+	}
+
+	switch f.Type().String() {
+	case "int", "int8", "int16", "int32", "int64":
+		return strconv.FormatInt(f.Int(), 10), nil
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return strconv.FormatUint(f.Uint(), 10), nil
+	case "float32":
+		return strconv.FormatFloat(f.Float(), floatFormat(options), -1, 32), nil
+	case "float64":
+		return strconv.FormatFloat(f.Float(), floatFormat(options), -1, 64), nil
+	case "string":
+		if f.String() == "" && !ptr {
+			return null, nil
+		}
+		return f.String(), nil
+	case "[]uint8":
+		return string(f.Bytes()), nil
+	case "net.IP", "net.HardwareAddr":
+		if f.Len() == 0 {
+			return null, nil
+		}
+		return f.Interface().(fmt.Stringer).String(), nil
+	case "bool":
+		return strconv.FormatBool(f.Bool()), nil
+	case "time.Duration":
+		return formatDuration(options.DurationUnit, time.Duration(f.Int())), nil
+	case "time.Time":
+		layout := time.RFC3339Nano
+		if len(options.TimeLayouts) > 0 {
+			layout = timeLayout(options.TimeLayouts[0])
+		}
+		return f.Interface().(time.Time).Format(layout), nil
+	}
+
+	if labels, ok := options.EnumMap[f.Type().String()]; ok && isIntegerKind(f.Kind()) {
+		return enumLabel(labels, f)
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(f.Float(), floatFormat(options), -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(f.Float(), floatFormat(options), -1, 64), nil
+	case reflect.String:
+		if f.String() == "" && !ptr {
+			return null, nil
+		}
+		return f.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", f.Type().String())
+}
+
+// enumLabel returns the label of labels whose value is that of the integer f, the first in sorted order when several
+// share it.
+func enumLabel(labels map[string]int64, f reflect.Value) (string, error) {
+	var value int64
+	if f.CanInt() {
+		value = f.Int()
+	} else {
+		value = int64(f.Uint())
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if labels[name] == value {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no %s label for value %d", f.Type().String(), value)
+}
@@ -0,0 +1,596 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripInner struct {
+	X int
+	Y string
+}
+
+type roundTrip struct {
+	I     int
+	I8    int8
+	I64   int64
+	U16   uint16
+	U     uint64
+	F     float64
+	F32   float32
+	S     string
+	B     bool
+	T     time.Time
+	D     time.Duration
+	Ints  []int
+	Bools [2]bool
+	M     map[string]int
+	Inner roundTripInner
+	P     *int
+	PS    *string
+	PT    *time.Time
+	Data  string `csv:"Data,base64,gzip"`
+	Raw   string `csv:"Raw,base64"`
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(int64(1), uint64(2), 3.5, "text", true, int64(1700000000), uint32(123456789), int64(90*time.Second), 7)
+	f.Add(int64(-300), uint64(math.MaxUint64), -0.0, "", false, int64(0), uint32(0), int64(0), 0)
+	f.Add(int64(math.MinInt64), uint64(0), 1e300, `a,"b"`+"\n c", true, int64(-62135596800), uint32(999999999), int64(-1), -1)
+	f.Add(int64(0), uint64(65536), math.SmallestNonzeroFloat64, " lead;a=b ", false, int64(253402300799), uint32(1), int64(math.MaxInt64), 1)
+
+	f.Fuzz(func(t *testing.T, i int64, u uint64, fl float64, s string, b bool, sec int64, nsec uint32, d int64, n int) {
+		if math.IsNaN(fl) {
+			t.Skip("NaN never compares equal")
+		}
+		if strings.Contains(s, "\r\n") {
+			t.Skip(`csv.Reader reads "\r\n" in a quoted field as "\n"`)
+		}
+		// Times are written with RFC3339Nano, which only covers years 0000 to 9999.
+		const minSec, maxSec = -62167219200, 253402300799
+		sec = minSec + (sec%(maxSec-minSec)+(maxSec-minSec))%(maxSec-minSec)
+
+		in := &roundTrip{
+			I:     int(i),
+			I8:    int8(i),
+			I64:   i,
+			U16:   uint16(u),
+			U:     u,
+			F:     fl,
+			F32:   float32(fl),
+			S:     s,
+			B:     b,
+			T:     time.Unix(sec, int64(nsec%1e9)).UTC(),
+			D:     time.Duration(d),
+			Ints:  []int{int(i), n},
+			Bools: [2]bool{b, !b},
+			M:     map[string]int{"a": n, "b": int(i)},
+			Inner: roundTripInner{X: n, Y: s},
+			Data:  s,
+			Raw:   s,
+		}
+		if b {
+			in.P = &n
+			in.PT = &in.T
+		}
+		if s != "" {
+			in.PS = &s
+		}
+
+		content, err := MarshalCSV(nil, []*roundTrip{in})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := ProcessCSV[roundTrip](nil, content)
+		if err != nil {
+			t.Fatalf("ProcessCSV(%q): %v", content, err)
+		}
+		if len(out) != 1 {
+			t.Fatalf("ProcessCSV(%q) returned %d records, want 1", content, len(out))
+		}
+		if !reflect.DeepEqual(out[0], in) {
+			t.Errorf("round trip of %q:\n got %+v\nwant %+v", content, *out[0], *in)
+		}
+	})
+}
+
+func TestMarshalCSVRoundTripStrings(t *testing.T) {
+	for _, s := range []string{"", " ", "#hash", `"quoted"`, "a,b", "line\nbreak", `\.`, "\ttab", "naïve", "cr\r"} {
+		in := &roundTripInner{X: 1, Y: s}
+		content, err := MarshalCSV(nil, []*roundTripInner{in})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := ProcessCSV[roundTripInner](nil, content)
+		if err != nil {
+			t.Fatalf("ProcessCSV(%q): %v", content, err)
+		}
+		if len(out) != 1 || *out[0] != *in {
+			t.Errorf("round trip of %q through %q gave %+v", s, content, out)
+		}
+	}
+}
+
+func TestMarshalCSVTrailingNewline(t *testing.T) {
+	off, on := false, true
+	tests := []struct {
+		name  string
+		value *bool
+		want  string
+	}{
+		{"default", nil, "X,Y\n1,a\n"},
+		{"true", &on, "X,Y\n1,a\n"},
+		{"false", &off, "X,Y\n1,a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalCSV(&Options{TrailingNewline: tt.value}, []*roundTripInner{{X: 1, Y: "a"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type nullable struct {
+	S  string
+	P  *int
+	PS *string
+	N  int
+}
+
+func TestMarshalCSVWriteNullAs(t *testing.T) {
+	zero, empty := 0, ""
+	full := "x"
+	ts := []*nullable{
+		{S: "", P: nil, PS: nil, N: 0},
+		{S: "a", P: &zero, PS: &empty, N: 1},
+		{S: "b", P: &zero, PS: &full, N: 2},
+	}
+	got, err := MarshalCSV(&Options{WriteNullAs: `\N`}, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "S,P,PS,N\n\\N,\\N,\\N,0\na,0,,1\nb,0,x,2\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMarshalCSVWriteNullAsElements(t *testing.T) {
+	type row struct {
+		Tags   []string
+		Labels map[string]string
+		Name   string
+	}
+	options := &Options{WriteNullAs: `\N`, NullValues: []string{`\N`}}
+	in := []*row{{Tags: []string{"", "b"}, Labels: map[string]string{"a": "", "b": "x"}}}
+	got, err := MarshalCSV(options, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Tags,Labels,Name\n;b,a=;b=x,\\N\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	out, err := ProcessCSV[row](options, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || !reflect.DeepEqual(out[0], in[0]) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+}
+
+func TestMarshalCSVZeroAsEmpty(t *testing.T) {
+	zero := 0
+	ts := []*nullable{
+		{S: "", P: nil, N: 0},
+		{S: "a", P: &zero, N: 0},
+		{S: "b", P: &zero, N: 3},
+	}
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"default", Options{}, "S,P,PS,N\n,,,0\na,0,,0\nb,0,,3\n"},
+		{"zero as empty", Options{ZeroAsEmpty: true}, "S,P,PS,N\n,,,\na,0,,\nb,0,,3\n"},
+		{"with WriteNullAs", Options{ZeroAsEmpty: true, WriteNullAs: "NULL"}, "S,P,PS,N\n,NULL,NULL,\na,0,NULL,\nb,0,NULL,3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalCSV(&tt.options, ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type address struct {
+	Street string
+	City   string
+}
+
+type contact struct {
+	Name    string
+	Tags    []string
+	Address address
+}
+
+func TestMarshalCSVSliceAndNestedRoundTrip(t *testing.T) {
+	in := []*contact{
+		{Name: "Ada", Tags: []string{"math", "engines"}, Address: address{"12 St James's Sq", "London"}},
+		{Name: "Grace", Tags: []string{"navy"}, Address: address{"1 Main St, Apt 2", "Arlington"}},
+	}
+	for _, options := range []*Options{nil, {SliceDelimiter: "|"}} {
+		content, err := MarshalCSV(options, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(content, "Name,Tags,Address.Street,Address.City\n") {
+			t.Errorf("got header %q, want dotted nested headers", strings.SplitN(content, "\n", 2)[0])
+		}
+		out, err := ProcessCSV[contact](options, content)
+		if err != nil {
+			t.Fatalf("ProcessCSV(%q): %v", content, err)
+		}
+		if len(out) != len(in) {
+			t.Fatalf("got %d records, want %d", len(out), len(in))
+		}
+		for i := range in {
+			if !reflect.DeepEqual(out[i], in[i]) {
+				t.Errorf("record %d = %+v, want %+v", i, *out[i], *in[i])
+			}
+		}
+	}
+}
+
+func TestWriteCSVGzip(t *testing.T) {
+	in := []*roundTripInner{{X: 1, Y: "a"}, {X: 2, Y: "b"}}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := WriteCSV(nil, zw, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ProcessCSV[roundTripInner](nil, string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+}
+
+func TestWriteCSVFlushesBufferedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	if err := WriteCSV(nil, bw, []*roundTripInner{{X: 1, Y: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "X,Y\n1,a\n"; got != want {
+		t.Errorf("got %q without flushing, want %q", got, want)
+	}
+}
+
+func TestWriteCSVFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.csv")
+	if err := WriteCSVFile(nil, name, []*roundTripInner{{X: 1, Y: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "X,Y\n1,a\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type measurement struct {
+	V float64
+}
+
+func TestMarshalCSVFloatFormat(t *testing.T) {
+	tests := []struct {
+		format byte
+		want   string
+	}{
+		{0, "V\n1.2345e+08\n"},
+		{'g', "V\n1.2345e+08\n"},
+		{'f', "V\n123450000\n"},
+		{'e', "V\n1.2345e+08\n"},
+	}
+	for _, tt := range tests {
+		got, err := MarshalCSV(&Options{FloatFormat: tt.format}, []*measurement{{V: 123450000}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("format %q: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	small := []*measurement{{V: 0.00012}}
+	for format, want := range map[byte]string{'g': "V\n0.00012\n", 'f': "V\n0.00012\n", 'e': "V\n1.2e-04\n"} {
+		got, err := MarshalCSV(&Options{FloatFormat: format}, small)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("format %q: got %q, want %q", format, got, want)
+		}
+	}
+}
+
+type jsonShared struct {
+	ID    int     `csv:"id,string" json:"id,string"`
+	Total float64 `csv:"total,string" json:"total,string"`
+	Name  string  `csv:"name" json:"name"`
+}
+
+func TestStringTagOption(t *testing.T) {
+	options := &Options{UseStructTags: true}
+	got, err := ProcessCSV[jsonShared](options, "id,total,name\n7,1.5,ada\n\"8\",\"2\",bob\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got[0] != (jsonShared{7, 1.5, "ada"}) || *got[1] != (jsonShared{8, 2, "bob"}) {
+		t.Errorf("got %+v and %+v, want fields matched despite the string option", *got[0], *got[1])
+	}
+
+	out, err := MarshalCSV(options, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,total,name\n\"7\",\"1.5\",ada\n\"8\",\"2\",bob\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMarshalCSVWriteBOM(t *testing.T) {
+	ts := []*pair{{"a", "b"}}
+	tests := []struct {
+		name     string
+		writeBOM bool
+		want     string
+	}{
+		{"without BOM", false, "A,B\na,b\n"},
+		{"with BOM", true, "\xef\xbb\xbfA,B\na,b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &Options{WriteBOM: tt.writeBOM}
+			out, err := MarshalCSV(options, ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Fatalf("got %q, want %q", out, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteCSV(options, &buf, ts); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteCSV wrote %q, want %q", buf.String(), tt.want)
+			}
+
+			got, err := ProcessCSV[pair](nil, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || *got[0] != *ts[0] {
+				t.Errorf("read back %+v, want the BOM stripped", got)
+			}
+		})
+	}
+}
+
+// failingFlusher is a writer whose Flush always fails.
+type failingFlusher struct {
+	bytes.Buffer
+}
+
+func (f *failingFlusher) Flush() error {
+	return errors.New("disk full")
+}
+
+func TestWriteCSVFlushEvery(t *testing.T) {
+	tests := []struct {
+		name       string
+		flushEvery int
+		want       string
+	}{
+		{"buffered", 0, ""},
+		{"every two records", 2, "A,B\n1,x\n2,x\n"},
+		{"every four records", 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ch := make(chan *pair)
+			done := make(chan error)
+			go func() { done <- WriteCSVChan(&Options{FlushEvery: tt.flushEvery}, &buf, ch) }()
+
+			// Each send returns once the previous record has been written, so after the third send the first two
+			// records have been written and, with FlushEvery 2, flushed, while the third is not flushed yet.
+			for _, a := range []string{"1", "2", "3"} {
+				ch <- &pair{a, "x"}
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("mid-stream output %q, want %q", got, tt.want)
+			}
+			close(ch)
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if want := "A,B\n1,x\n2,x\n3,x\n"; buf.String() != want {
+				t.Errorf("got %q, want %q", buf.String(), want)
+			}
+		})
+	}
+
+	err := WriteCSV(&Options{FlushEvery: 1}, &failingFlusher{}, []*pair{{"a", "b"}, {"c", "d"}})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("got error %v, want the flush error", err)
+	}
+}
+
+func TestMarshalCSVMap(t *testing.T) {
+	ints, err := MarshalCSVMap(nil, map[int]*pair{10: {"ten", "x"}, 9: {"nine", "y"}, -2: {"minus two", "z"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A,B\nminus two,z\nnine,y\nten,x\n"; ints != want {
+		t.Errorf("got %q, want rows in numeric key order %q", ints, want)
+	}
+
+	strs, err := MarshalCSVMap(nil, map[string]*pair{"b": {"2", ""}, "a": {"1", ""}, "B": {"0", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A,B\n0,\n1,\n2,\n"; strs != want {
+		t.Errorf("got %q, want rows in string key order %q", strs, want)
+	}
+
+	type key struct{ X, Y int }
+	structs, err := MarshalCSVMap(nil, map[key]*pair{{2, 1}: {"c", ""}, {1, 10}: {"b", ""}, {1, 2}: {"a", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A,B\nb,\na,\nc,\n"; structs != want {
+		t.Errorf("got %q, want rows in fmt.Sprint key order %q", structs, want)
+	}
+}
+
+func TestMarshalCSVMarshalFields(t *testing.T) {
+	in := []*contact{{Name: "Ada", Tags: []string{"math"}, Address: address{"12 St James's Sq", "London"}}}
+	got, err := MarshalCSV(&Options{MarshalFields: []string{"Address.City", "Name"}}, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Address.City,Name\nLondon,Ada\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	_, err = MarshalCSV(&Options{MarshalFields: []string{"Name", "Phone"}}, in)
+	if err == nil || !strings.Contains(err.Error(), "unknown marshal field: Phone") {
+		t.Errorf("got error %v, want an unknown marshal field error", err)
+	}
+}
+
+func TestMarshalCSVInterfaceRoundTrip(t *testing.T) {
+	type row struct {
+		ID    any
+		Label any
+		Note  any
+	}
+	options := &Options{FieldTypeHints: map[string]reflect.Type{"ID": reflect.TypeOf(int64(0))}}
+	in := []*row{{ID: int64(42), Label: "x", Note: nil}, {ID: int64(-7), Label: 1.5, Note: true}}
+	content, err := MarshalCSV(options, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ID,Label,Note\n42,x,\n-7,1.5,true\n"; content != want {
+		t.Errorf("got %q, want %q", content, want)
+	}
+
+	out, err := ProcessCSV[row](options, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []row{{ID: int64(42), Label: "x", Note: ""}, {ID: int64(-7), Label: "1.5", Note: "true"}}
+	for i := range want {
+		if !reflect.DeepEqual(*out[i], want[i]) {
+			t.Errorf("record %d = %+v, want %+v", i, *out[i], want[i])
+		}
+	}
+}
+
+type priority uint8
+
+type weight float32
+
+type queue string
+
+type urgency bool
+
+type ticket struct {
+	Status   status
+	Priority priority
+	Weight   weight
+	Queue    queue
+	Urgent   urgency
+}
+
+func TestMarshalCSVNamedTypesRoundTrip(t *testing.T) {
+	in := []*ticket{{Status: statusActive, Priority: 3, Weight: 0.5, Queue: "ops", Urgent: true}, {Status: statusInactive, Priority: 0, Weight: 2, Queue: "dev"}}
+	tests := []struct {
+		name    string
+		options *Options
+		want    string
+	}{
+		{"enum labels", &Options{EnumMap: map[string]map[string]int64{
+			"csv.status": {"INACTIVE": int64(statusInactive), "ACTIVE": int64(statusActive)},
+		}}, "Status,Priority,Weight,Queue,Urgent\nACTIVE,3,0.5,ops,true\nINACTIVE,0,2,dev,false\n"},
+		{"by kind", nil, "Status,Priority,Weight,Queue,Urgent\n1,3,0.5,ops,true\n0,0,2,dev,false\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := MarshalCSV(tt.options, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content != tt.want {
+				t.Errorf("got %q, want %q", content, tt.want)
+			}
+
+			out, err := ProcessCSV[ticket](tt.options, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range in {
+				if *out[i] != *in[i] {
+					t.Errorf("record %d = %+v, want %+v", i, *out[i], *in[i])
+				}
+			}
+		})
+	}
+
+	options := &Options{EnumMap: map[string]map[string]int64{"csv.status": {"ACTIVE": int64(statusActive)}}}
+	_, err := MarshalCSV(options, []*ticket{{Status: 9}})
+	if err == nil || !strings.Contains(err.Error(), "no csv.status label for value 9") {
+		t.Errorf("got error %v, want a missing label error", err)
+	}
+}
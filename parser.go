@@ -0,0 +1,27 @@
+package csv
+
+import (
+	"reflect"
+	"sync"
+)
+
+var parsers sync.Map // map[reflect.Type]func(string) (reflect.Value, error)
+
+// RegisterParser registers fn to parse cells into fields of type T, such as a type's ParseT constructor. Registered
+// parsers apply to all options and are consulted for types that are not built in and have no entry in
+// CustomMarshallingFuncMap. Registering a parser for T again replaces it. RegisterParser is safe for concurrent use.
+func RegisterParser[T any](fn func(string) (T, error)) {
+	parsers.Store(reflect.TypeOf((*T)(nil)).Elem(), func(s string) (reflect.Value, error) {
+		v, err := fn(s)
+		return reflect.ValueOf(&v).Elem(), err
+	})
+}
+
+// registeredParser returns the parser registered for t.
+func registeredParser(t reflect.Type) (func(string) (reflect.Value, error), bool) {
+	p, ok := parsers.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return p.(func(string) (reflect.Value, error)), true
+}
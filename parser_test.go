@@ -0,0 +1,63 @@
+package csv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type rgb uint32
+
+func parseRGB(s string) (rgb, error) {
+	if !strings.HasPrefix(s, "#") || len(s) != 7 {
+		return 0, fmt.Errorf("invalid color %q", s)
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 32)
+	return rgb(n), err
+}
+
+type swatch struct {
+	Name  string
+	Color rgb
+}
+
+func TestRegisterParser(t *testing.T) {
+	RegisterParser(parseRGB)
+
+	tests := []struct {
+		name    string
+		options *Options
+		row     string
+		want    swatch
+		wantErr bool
+	}{
+		{"parsed", nil, "red,#ff0000", swatch{"red", 0xff0000}, false},
+		{"invalid", nil, "red,ff0000", swatch{}, true},
+		{"invalid ignored", &Options{IgnoreFieldTypeErrors: true}, "red,ff0000", swatch{Name: "red"}, false},
+		{"custom func first", &Options{CustomMarshallingFuncMap: map[string]CustomMarshallingFunc{
+			"csv.rgb": func(v *reflect.Value, s string) error {
+				v.SetUint(1)
+				return nil
+			},
+		}}, "red,#ff0000", swatch{"red", 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[swatch](tt.options, "Name,Color\n"+tt.row+"\n")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid color") {
+					t.Fatalf("got error %v, want the parser's error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got[0] != tt.want {
+				t.Errorf("got %+v, want %+v", *got[0], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ProcessPGCopy processes the text format of Postgres COPY ... TO output: tab separated fields, backslash escapes
+// and \N for null. The output has no header, so fields are bound in declaration order unless Options.HeaderOverride
+// is set. The remaining options apply as for ProcessCSV. A field is null only when it is written as \N, so the
+// escaped text \\N is read as the string \N. Null fields leave pointer fields nil, while empty fields are empty
+// strings, so a *string field points to "". Options.NullValues are matched after unescaping.
+func ProcessPGCopy[T any](options *Options, rd io.Reader) ([]*T, error) {
+	copyOptions := *normalizeOptions(options)
+	copyOptions.Separator = '\t'
+	copyOptions.StringSeparator = ""
+	copyOptions.EscapeChar = '\\'
+	copyOptions.Comment = 0
+	copyOptions.AutoDetectSeparator = false
+
+	if len(copyOptions.HeaderOverride) == 0 {
+		rt := reflect.TypeOf((*T)(nil)).Elem()
+		if rt.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("expected struct, got %s", rt.Kind())
+		}
+		copyOptions.HeaderOverride, _ = marshalColumns(rt, "", copyOptions.UseStructTags)
+	}
+	r := newRecordReader(&copyOptions, rd)
+	r.(*escapeReader).copyFormat = true
+	return processRecords(&copyOptions, r, processHooks[T]{})
+}
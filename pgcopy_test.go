@@ -0,0 +1,75 @@
+package csv
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type copyRow struct {
+	ID    int
+	Name  string
+	Note  *string
+	Score *float64
+}
+
+func TestProcessPGCopy(t *testing.T) {
+	content := "1\tAda\\tLovelace\tline\\none\t9.5\n2\tGrace\t\\N\t\\N\n"
+	got, err := ProcessPGCopy[copyRow](nil, strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d rows, want 2", len(got))
+	}
+	if r := got[0]; r.ID != 1 || r.Name != "Ada\tLovelace" || r.Note == nil || *r.Note != "line\none" || r.Score == nil || *r.Score != 9.5 {
+		t.Errorf("row 0 = %+v, want escapes resolved", *r)
+	}
+	if r := got[1]; r.ID != 2 || r.Name != "Grace" || r.Note != nil || r.Score != nil {
+		t.Errorf("row 1 = %+v, want \\N read as null", *r)
+	}
+
+	tests := []struct {
+		name string
+		note string // note is the raw COPY text of the Note field
+		want *string
+	}{
+		{"null", `\N`, nil},
+		{"empty string", ``, ptrTo("")},
+		{"escaped backslash N", `\\N`, ptrTo(`\N`)},
+		{"N inside text", `a\Nb`, ptrTo("aNb")},
+		{"control escapes", `\b\f\v\r`, ptrTo("\b\f\v\r")},
+		{"octal", `\101\0121`, ptrTo("A\n1")},
+		{"short octal", `\7x`, ptrTo("\ax")},
+		{"hex", `\x41\x4a\x7`, ptrTo("AJ\a")},
+		{"bare x", `\xyz`, ptrTo("xyz")},
+		{"UTF-8 bytes", `\303\251`, ptrTo("é")},
+		{"other character", `\q\\`, ptrTo(`q\`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessPGCopy[copyRow](nil, strings.NewReader("1\tAda\t"+tt.note+"\t\\N\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if note := got[0].Note; (note == nil) != (tt.want == nil) || note != nil && *note != *tt.want {
+				t.Errorf("got %v, want %v", fmtPtr(note), fmtPtr(tt.want))
+			}
+		})
+	}
+
+	got, err = ProcessPGCopy[copyRow](&Options{HeaderOverride: []string{"Name", "ID"}}, strings.NewReader("Ada\t7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Name != "Ada" || got[0].ID != 7 {
+		t.Errorf("with HeaderOverride got %+v", *got[0])
+	}
+}
+
+func fmtPtr(s *string) string {
+	if s == nil {
+		return "nil"
+	}
+	return strconv.Quote(*s)
+}
@@ -0,0 +1,129 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ProcessPolymorphic processes CSV input whose rows map to different structs depending on the value of the typeField
+// column. For each row the registry entry for that value constructs a pointer to a struct, which the row is
+// unmarshalled into. Rows with an unregistered type are skipped when IgnoreUnknownFields is set and are an error
+// otherwise. The typeField column is only bound to structs that have a matching field. Validate applies as in
+// ProcessCSV, and the header options HeaderRows, HeaderOverride and CommentHeaderOnly are supported; the other options
+// acting across records, listed in recordOptions, are an error when set.
+func ProcessPolymorphic(options *Options, content string, typeField string, registry map[string]func() any) ([]any, error) {
+	options = normalizeOptions(options)
+	if name, ok := unsupportedOption(options, "Validate", "HeaderRows", "HeaderOverride", "CommentHeaderOnly"); ok {
+		return nil, fmt.Errorf("ProcessPolymorphic does not support %s", name)
+	}
+	r := newRecordReader(options, strings.NewReader(content))
+
+	headers, err := readHeader(options, r)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv: %w", err)
+	}
+
+	typeColumn := -1
+	for i, header := range headers {
+		if header == typeField {
+			typeColumn = i
+			break
+		}
+	}
+	if typeColumn < 0 {
+		return nil, fmt.Errorf("type field %s not found in header", typeField)
+	}
+
+	vs := []any{}
+	mappings := map[reflect.Type][]FieldBinding{}
+	row := 0
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv: %w", err)
+		}
+		if typeColumn >= len(record) {
+			return nil, fmt.Errorf("error unmarshalling record: missing type field %s", typeField)
+		}
+
+		construct, ok := registry[record[typeColumn]]
+		if !ok {
+			if options.IgnoreUnknownFields {
+				continue
+			}
+			return nil, fmt.Errorf("error unmarshalling record: unregistered type %q", record[typeColumn])
+		}
+		v := construct()
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("registry entry for %q must return a pointer to a struct, got %T", record[typeColumn], v)
+		}
+
+		mapping, ok := mappings[rv.Type()]
+		if !ok {
+			mapping, err = polymorphicBindings(options, rv.Elem().Type(), headers, typeColumn)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshalling record: %w", err)
+			}
+			mappings[rv.Type()] = mapping
+		}
+
+		if err := unmarshalMapped(options, mapping, record, quotedFields(r), rv.Elem()); err != nil {
+			return nil, fmt.Errorf("error unmarshalling record: %w", err)
+		}
+		if options.Validate {
+			if options.Validator == nil {
+				return nil, fmt.Errorf("validation enabled but no Validator set")
+			}
+			if err := options.Validator(v); err != nil {
+				return nil, fmt.Errorf("row %d: validation failed: %w", row, err)
+			}
+		}
+		vs = append(vs, v)
+	}
+
+	return vs, nil
+}
+
+// polymorphicBindings resolves headers for rt, leaving the type column unbound when rt has no field for it.
+func polymorphicBindings(options *Options, rt reflect.Type, headers []string, typeColumn int) ([]FieldBinding, error) {
+	others := append(append([]string{}, headers[:typeColumn]...), headers[typeColumn+1:]...)
+	mapping, err := newFieldBindings(options, rt, others)
+	if err != nil {
+		return nil, err
+	}
+
+	lenient := *options
+	lenient.IgnoreUnknownFields = true
+	typeBinding, err := newFieldBindings(&lenient, rt, headers[typeColumn:typeColumn+1])
+	if err != nil {
+		return nil, err
+	}
+
+	shifted := map[*Composition]bool{}
+	for _, b := range mapping {
+		for _, c := range b.Compose {
+			if shifted[c] {
+				continue
+			}
+			for k, col := range c.Sources {
+				if col >= typeColumn {
+					c.Sources[k] = col + 1
+				}
+			}
+			shifted[c] = true
+		}
+	}
+	mapping = append(mapping[:typeColumn], append(typeBinding, mapping[typeColumn:]...)...)
+	return mapping, nil
+}
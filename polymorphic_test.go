@@ -0,0 +1,98 @@
+package csv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type clickEvent struct {
+	Type string
+	X    int
+	Y    int
+}
+
+type keyEvent struct {
+	Key string
+}
+
+func eventRegistry() map[string]func() any {
+	return map[string]func() any{
+		"click": func() any { return &clickEvent{} },
+		"key":   func() any { return &keyEvent{} },
+	}
+}
+
+func TestProcessPolymorphic(t *testing.T) {
+	content := "Type,X,Y,Key\nclick,1,2,\nkey,,,a\nclick,3,4,\n"
+	vs, err := ProcessPolymorphic(&Options{IgnoreUnknownFields: true}, content, "Type", eventRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 3 {
+		t.Fatalf("got %d values, want 3", len(vs))
+	}
+	if c, ok := vs[0].(*clickEvent); !ok || *c != (clickEvent{"click", 1, 2}) {
+		t.Errorf("value 0 = %#v", vs[0])
+	}
+	if k, ok := vs[1].(*keyEvent); !ok || k.Key != "a" {
+		t.Errorf("value 1 = %#v", vs[1])
+	}
+	if c, ok := vs[2].(*clickEvent); !ok || *c != (clickEvent{"click", 3, 4}) {
+		t.Errorf("value 2 = %#v", vs[2])
+	}
+}
+
+func TestProcessPolymorphicUnregistered(t *testing.T) {
+	content := "Type,Key\nkey,a\nscroll,b\n"
+	if _, err := ProcessPolymorphic(nil, content, "Type", eventRegistry()); err == nil {
+		t.Error("expected an error for an unregistered type")
+	}
+	vs, err := ProcessPolymorphic(&Options{IgnoreUnknownFields: true}, content, "Type", eventRegistry())
+	if err != nil || len(vs) != 1 {
+		t.Errorf("got %d values and %v, want the registered row only", len(vs), err)
+	}
+}
+
+func TestProcessPolymorphicUnsupportedOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+	}{
+		{"Deduplicate", &Options{Deduplicate: true}},
+		{"StopFunc", &Options{StopFunc: func(headers, record []string) bool { return false }}},
+		{"InferAndEnforceTypes", &Options{InferAndEnforceTypes: true}},
+		{"MinFields", &Options{MinFields: 1}},
+		{"MaxFields", &Options{MaxFields: 5}},
+		{"CollectErrors", &Options{CollectErrors: true}},
+		{"KeyValueMode", &Options{KeyValueMode: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ProcessPolymorphic(tt.options, "Type,Key\nkey,a\n", "Type", eventRegistry()); err == nil {
+				t.Errorf("expected %s to be rejected", tt.name)
+			}
+		})
+	}
+}
+
+func TestProcessPolymorphicValidate(t *testing.T) {
+	options := &Options{
+		IgnoreUnknownFields: true,
+		Validate:            true,
+		Validator: func(v interface{}) error {
+			if c, ok := v.(*clickEvent); ok && c.X < 0 {
+				return errors.New("negative X")
+			}
+			return nil
+		},
+	}
+	content := "Type,X,Y,Key\nclick,1,2,\nkey,,,a\n"
+	if vs, err := ProcessPolymorphic(options, content, "Type", eventRegistry()); err != nil || len(vs) != 2 {
+		t.Errorf("got %d values and %v, want both rows", len(vs), err)
+	}
+	_, err := ProcessPolymorphic(options, content+"click,-1,0,\n", "Type", eventRegistry())
+	if err == nil || !strings.Contains(err.Error(), "row 3: validation failed: negative X") {
+		t.Errorf("got %v, want a validation error for row 3", err)
+	}
+}
@@ -0,0 +1,65 @@
+package csv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProcessCSVWithPreamble processes CSV input preceded by metadata lines such as "# Date: 2024-01-01". The preamble is
+// the run of leading lines starting with the Comment character ('#' when Comment is unset) and ends at the first line
+// that does not. Preamble lines of the form "key: value" are returned in a map with surrounding space trimmed; other
+// preamble lines are skipped. The remaining content is processed with ProcessCSV.
+func ProcessCSVWithPreamble[T any](options *Options, content string) (map[string]string, []*T, error) {
+	options = normalizeOptions(options)
+	comment := options.Comment
+	if comment == 0 {
+		comment = '#'
+	}
+
+	preamble := map[string]string{}
+	rest := content
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		if !strings.HasPrefix(line, string(comment)) {
+			break
+		}
+		line = strings.TrimSuffix(strings.TrimPrefix(line, string(comment)), "\r")
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			preamble[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		rest = next
+	}
+
+	ts, err := ProcessCSV[T](options, rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return preamble, ts, nil
+}
+
+// ProcessCSVBetween processes the CSV block embedded in content between the first startMarker and the following
+// endMarker. The remainder of the line holding startMarker is discarded, so markers may sit on their own lines. An
+// error is returned when either marker is missing or endMarker only appears before startMarker.
+func ProcessCSVBetween[T any](options *Options, content, startMarker, endMarker string) ([]*T, error) {
+	start := strings.Index(content, startMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("start marker %q not found", startMarker)
+	}
+	block := content[start+len(startMarker):]
+
+	end := strings.Index(block, endMarker)
+	if end < 0 {
+		if strings.Contains(content[:start], endMarker) {
+			return nil, fmt.Errorf("end marker %q appears before start marker %q", endMarker, startMarker)
+		}
+		return nil, fmt.Errorf("end marker %q not found", endMarker)
+	}
+	block = block[:end]
+
+	if _, rest, ok := strings.Cut(block, "\n"); ok {
+		block = rest
+	} else {
+		block = ""
+	}
+	return ProcessCSV[T](options, block)
+}
@@ -0,0 +1,54 @@
+package csv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcessCSVBetween(t *testing.T) {
+	content := "log started\nA,B ignored\n---BEGIN---\nA,B\na,b\nc,d\n---END---\ntrailing noise, more\n"
+	got, err := ProcessCSVBetween[pair](nil, content, "---BEGIN---", "---END---")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || *got[0] != (pair{"a", "b"}) || *got[1] != (pair{"c", "d"}) {
+		t.Errorf("got %+v", got)
+	}
+
+	for name, content := range map[string]string{
+		"no start":     "A,B\na,b\n---END---\n",
+		"no end":       "---BEGIN---\nA,B\na,b\n",
+		"out of order": "---END---\nA,B\n---BEGIN---\n",
+	} {
+		if _, err := ProcessCSVBetween[pair](nil, content, "---BEGIN---", "---END---"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestProcessCSVWithPreamble(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		content string
+		want    map[string]string
+	}{
+		{"default comment", nil, "# Date: 2024-01-01\r\n# Source :  export\n# generated by tool\nA,B\na,b\nc,d\n", map[string]string{"Date": "2024-01-01", "Source": "export"}},
+		{"custom comment", &Options{Comment: ';'}, "; Owner: ops\nA,B\na,b\nc,d\n", map[string]string{"Owner": "ops"}},
+		{"no preamble", nil, "A,B\na,b\nc,d\n", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preamble, got, err := ProcessCSVWithPreamble[pair](tt.options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(preamble, tt.want) {
+				t.Errorf("got preamble %v, want %v", preamble, tt.want)
+			}
+			if len(got) != 2 || *got[0] != (pair{"a", "b"}) || *got[1] != (pair{"c", "d"}) {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
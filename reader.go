@@ -0,0 +1,888 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// recordReader reads one record at a time. It is satisfied by *csv.Reader and by the readers for the non-CSV
+// formats below.
+type recordReader interface {
+	Read() (record []string, err error)
+}
+
+// newRecordReader returns the reader selected by options. A UTF-8 byte order mark at the start of rd is skipped. With
+// CommentHeaderOnly, comment lines are skipped only before the first record, whether that is the header or, with
+// HeaderOverride, the first data record.
+func newRecordReader(options *Options, rd io.Reader) recordReader {
+	r := newFormatReader(options, rd)
+	if options.CommentHeaderOnly && options.Comment != 0 {
+		return &commentHeaderOnlyReader{r: r}
+	}
+	return r
+}
+
+// newFormatReader returns the reader of the input format selected by options.
+func newFormatReader(options *Options, rd io.Reader) recordReader {
+	rd = skipBOM(rd)
+	if options.MaxLineBytes > 0 {
+		rd = newRecordLimitReader(options, rd)
+	}
+	if options.CommentPrefix != "" {
+		rd = &commentPrefixReader{r: bufio.NewReader(rd), prefix: options.CommentPrefix}
+	}
+	if options.AutoDetectSeparator {
+		detected := *options
+		rd, detected.Separator = sniffingReader(options, rd)
+		options = &detected
+	}
+	if sep := options.StringSeparator; sep != "" {
+		if utf8.RuneCountInString(sep) > 1 {
+			return &multiSeparatorReader{r: bufio.NewReader(rd), sep: sep, comment: options.Comment}
+		}
+		single := *options
+		single.Separator, _ = utf8.DecodeRuneInString(sep)
+		options = &single
+	}
+	if options.EscapeChar != 0 {
+		sep := options.Separator
+		if sep == 0 {
+			sep = ','
+		}
+		return &escapeReader{r: bufio.NewReader(rd), sep: sep, esc: options.EscapeChar, comment: options.Comment}
+	}
+	if options.WhitespaceDelimited {
+		return &whitespaceReader{s: newLineScanner(rd), comment: options.Comment}
+	}
+	if options.RecordSeparator != 0 && options.RecordSeparator != '\n' {
+		s := newLineScanner(rd)
+		s.Split(splitOn(options.RecordSeparator))
+		return &recordSeparatorReader{s: s, options: options}
+	}
+	if options.RepairQuotes {
+		return &quoteRepairReader{r: bufio.NewReader(rd), options: options}
+	}
+	if options.QuotedEmptyAsValue {
+		return newQuoteScanReader(options, rd)
+	}
+	return newCSVReader(options, rd)
+}
+
+// newLineScanner returns a scanner of the lines of rd without bufio.Scanner's default limit on token length, so that
+// long lines, or long records under another split function, are read whole as they are by csv.Reader.
+func newLineScanner(rd io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(rd)
+	s.Buffer(nil, math.MaxInt)
+	return s
+}
+
+// skipBOM returns a reader of rd without its leading UTF-8 byte order mark, if any.
+func skipBOM(rd io.Reader) io.Reader {
+	br := bufio.NewReader(rd)
+	if b, _ := br.Peek(len(utf8BOM)); bytes.Equal(b, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
+
+// recordLimitReader fails once a record grows past max bytes. A record ends at a newline outside quotes. As in
+// csv.Reader, a quote opens a quoted field only at the start of a field, after leading space with TrimLeadingSpace,
+// and a doubled quote inside it is literal, so an unterminated quote makes the rest of the input count as one record.
+// Without quote tracking, as with LazyQuotes, EscapeChar or WhitespaceDelimited where quotes may be literal, every
+// newline ends a record. The bytes before the limit are returned before the error.
+type recordLimitReader struct {
+	r          io.Reader
+	max        int
+	sep        []byte // sep is the field separator
+	track      bool   // track is whether quotes open quoted fields
+	trimSpace  bool   // trimSpace is whether leading space is skipped before an opening quote
+	n          int    // n is the number of bytes read since the last record boundary
+	quoted     bool   // quoted is set inside a quoted field
+	closing    bool   // closing is set after a quote inside a quoted field, which a second quote makes literal
+	fieldStart bool   // fieldStart is set at the start of a field outside quotes
+	recent     []byte // recent are the last bytes of the current field, up to the length of sep
+	line       int    // line is the current line number
+	start      int    // start is the line on which the current record started
+	err        error
+}
+
+func newRecordLimitReader(options *Options, rd io.Reader) *recordLimitReader {
+	return &recordLimitReader{
+		r:          rd,
+		max:        options.MaxLineBytes,
+		sep:        []byte(fieldSeparator(options)),
+		track:      !options.LazyQuotes && options.EscapeChar == 0 && !options.WhitespaceDelimited,
+		trimSpace:  options.TrimLeadingSpace,
+		fieldStart: true,
+		line:       1,
+		start:      1,
+	}
+}
+
+func (l *recordLimitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.r.Read(p)
+	for i, c := range p[:n] {
+		l.n++
+		l.scan(c)
+		if l.n > l.max {
+			l.err = fmt.Errorf("record starting on line %d exceeds %d bytes: possible unterminated quote", l.start, l.max)
+			return i, nil
+		}
+	}
+	return n, err
+}
+
+// scan advances the quote and record state past c.
+func (l *recordLimitReader) scan(c byte) {
+	if l.closing {
+		l.closing = false
+		if c == '"' {
+			return
+		}
+		l.quoted = false
+	}
+	if l.quoted {
+		switch c {
+		case '"':
+			l.closing = true
+		case '\n':
+			l.line++
+		}
+		return
+	}
+
+	switch {
+	case c == '\n':
+		l.line++
+		l.n = 0
+		l.start = l.line
+		l.fieldStart = true
+		l.recent = l.recent[:0]
+	case c == '"' && l.track && l.fieldStart:
+		l.quoted = true
+		l.fieldStart = false
+	default:
+		l.recent = append(l.recent, c)
+		if len(l.recent) > len(l.sep) {
+			l.recent = l.recent[1:]
+		}
+		if bytes.Equal(l.recent, l.sep) {
+			l.fieldStart = true
+			l.recent = l.recent[:0]
+		} else if !l.trimSpace || (c != ' ' && c != '\t') {
+			l.fieldStart = false
+		}
+	}
+}
+
+// commentPrefixReader drops the lines of r that begin with prefix. Lines are filtered before CSV parsing, so a line
+// inside a multi-line quoted field is dropped too when it begins with prefix.
+type commentPrefixReader struct {
+	r      *bufio.Reader
+	prefix string
+	line   []byte // line is the unread rest of the current kept line
+}
+
+func (c *commentPrefixReader) Read(p []byte) (int, error) {
+	for len(c.line) == 0 {
+		line, err := c.r.ReadBytes('\n')
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte(c.prefix)) {
+			c.line = line
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.line)
+	c.line = c.line[n:]
+	return n, nil
+}
+
+// utf8BOM is the UTF-8 encoding of the byte order mark U+FEFF.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// StripBOM returns s without a leading UTF-8 byte order mark. The readers of this package always skip a leading byte
+// order mark, so StripBOM is only needed for content handled elsewhere, where it would otherwise be read as part of
+// the first header.
+func StripBOM(s string) string {
+	return strings.TrimPrefix(s, string(utf8BOM))
+}
+
+// newCSVReader returns a csv.Reader configured from options.
+func newCSVReader(options *Options, rd io.Reader) *csv.Reader {
+	r := csv.NewReader(rd)
+	if options.Separator != 0 {
+		r.Comma = options.Separator
+	}
+	if options.LazyQuotes {
+		r.LazyQuotes = true
+	}
+	if options.FieldsPerRecord != 0 {
+		r.FieldsPerRecord = options.FieldsPerRecord
+	}
+	if options.EnforceHeaderWidth || options.JoinTrailingInto != "" || options.KeyValueMode || options.MinFields > 0 || options.MaxFields > 0 {
+		r.FieldsPerRecord = -1
+	}
+	if options.TrimLeadingSpace {
+		r.TrimLeadingSpace = true
+	}
+	if options.Comment != 0 {
+		r.Comment = options.Comment
+	}
+	return r
+}
+
+// whitespaceReader splits each line on runs of white space. Blank lines and lines starting with the comment
+// character are skipped. Quoting is not supported.
+type whitespaceReader struct {
+	s       *bufio.Scanner
+	comment rune
+}
+
+func (r *whitespaceReader) Read() ([]string, error) {
+	for r.s.Scan() {
+		line := r.s.Text()
+		if r.comment != 0 && strings.HasPrefix(line, string(r.comment)) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		return fields, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// escapeReader splits records on newlines and fields on sep, with esc escaping the following character instead of
+// quoting. esc followed by sep, esc, a newline or 'n' yields that separator, esc, or a newline respectively, and esc
+// followed by 't' or 'r' yields a tab or carriage return. Other escapes such as MySQL's \N are kept verbatim. Quotes
+// have no special meaning. Blank lines and lines starting with the comment character are skipped. FieldsPerRecord is
+// not enforced in this mode.
+//
+// With copyFormat, escapes follow the text format of Postgres COPY instead: \b, \f, \n, \r, \t and \v, octal \NNN
+// and hex \xNN byte values, and esc before any other character yields that character. A field written as \N, before
+// unescaping, is null; nulls reports which empty fields of the last record were null.
+type escapeReader struct {
+	r          *bufio.Reader
+	sep        rune
+	esc        rune
+	comment    rune
+	copyFormat bool
+	nulls      []bool // nulls[i] reports whether field i of the last record was \N; set with copyFormat only
+}
+
+func (r *escapeReader) Read() ([]string, error) {
+	for {
+		record, err := r.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return record, nil
+		}
+	}
+}
+
+// readRecord reads one line, returning a nil record for blank and comment lines.
+func (r *escapeReader) readRecord() ([]string, error) {
+	var record []string
+	var field strings.Builder
+	r.nulls = r.nulls[:0]
+	null := false // null is whether the field read so far is exactly \N
+	start := true
+loop:
+	for {
+		c, _, err := r.r.ReadRune()
+		if err == io.EOF {
+			if start {
+				return nil, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start && r.comment != 0 && c == r.comment {
+			if _, err := r.r.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+			return nil, nil
+		}
+		start = false
+
+		switch c {
+		case r.esc:
+			next, _, err := r.r.ReadRune()
+			if err == io.EOF {
+				return nil, fmt.Errorf("escape character %q at end of input", r.esc)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if r.copyFormat {
+				wasEmpty := field.Len() == 0 && !null
+				if err := r.unescapeCopy(&field, next); err != nil {
+					return nil, err
+				}
+				null = wasEmpty && next == 'N'
+				continue
+			}
+			switch next {
+			case r.sep, r.esc, '\n':
+				field.WriteRune(next)
+			case 'n':
+				field.WriteRune('\n')
+			case 't':
+				field.WriteRune('\t')
+			case 'r':
+				field.WriteRune('\r')
+			default:
+				field.WriteRune(c)
+				field.WriteRune(next)
+			}
+		case r.sep:
+			record = r.appendField(record, field.String(), null)
+			field.Reset()
+		case '\n':
+			break loop
+		default:
+			field.WriteRune(c)
+		}
+		null = false
+	}
+
+	last := strings.TrimSuffix(field.String(), "\r")
+	if record == nil && last == "" && !null {
+		return nil, nil
+	}
+	return r.appendField(record, last, null), nil
+}
+
+// appendField appends field to record, recording whether it was null with copyFormat. A null field is appended as
+// the empty string.
+func (r *escapeReader) appendField(record []string, field string, null bool) []string {
+	if r.copyFormat {
+		r.nulls = append(r.nulls, null)
+		if null {
+			field = ""
+		}
+	}
+	return append(record, field)
+}
+
+// unescapeCopy writes the value of the COPY escape sequence beginning with next, the character after esc, to field.
+// Octal and hex escapes denote single bytes. \N is written as N; readRecord tells a null field apart.
+func (r *escapeReader) unescapeCopy(field *strings.Builder, next rune) error {
+	switch next {
+	case 'b':
+		field.WriteByte('\b')
+	case 'f':
+		field.WriteByte('\f')
+	case 'n':
+		field.WriteByte('\n')
+	case 'r':
+		field.WriteByte('\r')
+	case 't':
+		field.WriteByte('\t')
+	case 'v':
+		field.WriteByte('\v')
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		n := int(next - '0')
+		for i := 0; i < 2; i++ {
+			d, err := r.peekDigit(8)
+			if err != nil {
+				return err
+			}
+			if d < 0 {
+				break
+			}
+			n = n*8 + d
+		}
+		field.WriteByte(byte(n))
+	case 'x':
+		d, err := r.peekDigit(16)
+		if err != nil {
+			return err
+		}
+		if d < 0 {
+			field.WriteByte('x')
+			return nil
+		}
+		n := d
+		if d, err = r.peekDigit(16); err != nil {
+			return err
+		}
+		if d >= 0 {
+			n = n*16 + d
+		}
+		field.WriteByte(byte(n))
+	default:
+		field.WriteRune(next)
+	}
+	return nil
+}
+
+// peekDigit consumes and returns the next byte of input when it is a digit in base, or returns -1 without consuming
+// it.
+func (r *escapeReader) peekDigit(base int) (int, error) {
+	b, err := r.r.Peek(1)
+	if err == io.EOF {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	d := -1
+	switch c := b[0]; {
+	case c >= '0' && c <= '9':
+		d = int(c - '0')
+	case c >= 'a' && c <= 'f':
+		d = int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		d = int(c-'A') + 10
+	}
+	if d < 0 || d >= base {
+		return -1, nil
+	}
+	r.r.Discard(1)
+	return d, nil
+}
+
+// terminatedReader reads from r and then, if the input was non-empty and did not end in a newline, a final newline.
+type terminatedReader struct {
+	r       io.Reader
+	last    byte
+	eof     bool
+	pending bool // pending is set when the final newline did not fit in the buffer that reached EOF
+}
+
+func (t *terminatedReader) Read(p []byte) (int, error) {
+	if t.pending && len(p) > 0 {
+		t.pending = false
+		p[0] = '\n'
+		return 1, io.EOF
+	}
+	if t.eof {
+		return 0, io.EOF
+	}
+
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.last = p[n-1]
+	}
+	if err == io.EOF {
+		t.eof = true
+		if t.last != 0 && t.last != '\n' {
+			if n < len(p) {
+				p[n] = '\n'
+				return n + 1, io.EOF
+			}
+			t.pending = true
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+// commentHeaderOnlyReader stops r from skipping comment lines once it has read the first record.
+type commentHeaderOnlyReader struct {
+	r    recordReader
+	read bool // read is set once the first record is read
+}
+
+func (c *commentHeaderOnlyReader) Read() ([]string, error) {
+	record, err := c.r.Read()
+	if err == nil && !c.read {
+		c.read = true
+		disableComments(c.r)
+	}
+	return record, err
+}
+
+// disableComments stops r from skipping comment lines in the records it reads next.
+func disableComments(r recordReader) {
+	switch r := r.(type) {
+	case *csv.Reader:
+		r.Comment = 0
+	case *quoteScanReader:
+		r.r.Comment = 0
+	case *whitespaceReader:
+		r.comment = 0
+	case *escapeReader:
+		r.comment = 0
+	case *multiSeparatorReader:
+		r.comment = 0
+	case *recordSeparatorReader:
+		options := *r.options
+		options.Comment = 0
+		r.options = &options
+	case *quoteRepairReader:
+		options := *r.options
+		options.Comment = 0
+		r.options = &options
+	}
+}
+
+// multiSeparatorReader splits records on newlines and fields on a separator of several characters, such as "||". A
+// field that begins with a quote is quoted: it ends at the next quote not doubled, may span lines, and must be followed
+// by the separator or the end of the record. Quotes elsewhere are literal. Blank lines and lines starting with the
+// comment character are skipped. LazyQuotes, TrimLeadingSpace and FieldsPerRecord are not supported in this mode.
+type multiSeparatorReader struct {
+	r       *bufio.Reader
+	sep     string
+	comment rune
+	line    int
+}
+
+func (r *multiSeparatorReader) Read() ([]string, error) {
+	var text string
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			if text != "" {
+				return nil, fmt.Errorf("record on line %d: unterminated quoted field", r.line)
+			}
+			return nil, io.EOF
+		}
+		r.line++
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if text == "" {
+			if line == "" || (r.comment != 0 && strings.HasPrefix(line, string(r.comment))) {
+				continue
+			}
+			text = line
+		} else {
+			text += "\n" + line
+		}
+
+		record, complete, perr := splitMultiSeparator(text, r.sep)
+		if perr != nil {
+			return nil, fmt.Errorf("record on line %d: %w", r.line, perr)
+		}
+		if complete {
+			return record, nil
+		}
+	}
+}
+
+// splitMultiSeparator splits the record text on sep. complete is false when text ends inside a quoted field.
+func splitMultiSeparator(text, sep string) (fields []string, complete bool, err error) {
+	i := 0
+	for {
+		if i < len(text) && text[i] == '"' {
+			var field strings.Builder
+			i++
+			for {
+				j := strings.IndexByte(text[i:], '"')
+				if j < 0 {
+					return nil, false, nil
+				}
+				field.WriteString(text[i : i+j])
+				i += j + 1
+				if i < len(text) && text[i] == '"' {
+					field.WriteByte('"')
+					i++
+					continue
+				}
+				break
+			}
+			fields = append(fields, field.String())
+			if i == len(text) {
+				return fields, true, nil
+			}
+			if !strings.HasPrefix(text[i:], sep) {
+				return nil, false, fmt.Errorf("extraneous text after quoted field")
+			}
+			i += len(sep)
+			continue
+		}
+
+		j := strings.Index(text[i:], sep)
+		if j < 0 {
+			return append(fields, text[i:]), true, nil
+		}
+		fields = append(fields, text[i:i+j])
+		i += j + len(sep)
+	}
+}
+
+// splitOn returns a bufio.SplitFunc that splits on sep.
+func splitOn(sep rune) bufio.SplitFunc {
+	delim := []byte(string(sep))
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// recordSeparatorReader splits the input into records on a custom separator and parses the fields of each record
+// with a csv.Reader. A record separator inside a quoted field ends the record, and newlines inside a record are only
+// supported within quotes. FieldsPerRecord is not enforced in this mode.
+type recordSeparatorReader struct {
+	s       *bufio.Scanner
+	options *Options
+}
+
+func (r *recordSeparatorReader) Read() ([]string, error) {
+	for r.s.Scan() {
+		cr := newCSVReader(r.options, strings.NewReader(r.s.Text()))
+		cr.FieldsPerRecord = -1
+		record, err := cr.Read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := cr.Read(); err != io.EOF {
+			return nil, fmt.Errorf("record contains an unquoted newline")
+		}
+		return record, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// repairMaxLines is the most lines a quoteRepairReader joins while looking for the end of a quoted field.
+const repairMaxLines = 100
+
+// quoteRepairReader parses each record like a csv.Reader and repairs records with unbalanced or stray quotes. A line
+// whose quotes are unbalanced is first joined with following lines, up to repairMaxLines, in case it starts a
+// multi-line quoted field. When that does not parse, the line is repaired on its own: it is parsed with LazyQuotes,
+// and unless that yields as many fields as the first record, split on the separator with one outer quote stripped
+// from each field and doubled quotes undone.
+// Repaired records are counted in Stats.RepairedRows.
+type quoteRepairReader struct {
+	r       *bufio.Reader
+	options *Options
+	pending []string // pending are lines read ahead and not yet parsed
+	width   int      // width is the number of fields in the first record
+}
+
+func (q *quoteRepairReader) Read() ([]string, error) {
+	for {
+		line, err := q.nextLine()
+		if err != nil {
+			return nil, err
+		}
+
+		record, ok, err := q.parse(line, q.options.LazyQuotes)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if record == nil {
+				continue
+			}
+			return q.record(record), nil
+		}
+
+		if strings.Count(line, `"`)%2 == 1 {
+			if record, ok := q.parseMultiLine(line); ok {
+				return q.record(record), nil
+			}
+		}
+
+		if q.options.Stats != nil {
+			q.options.Stats.RepairedRows++
+		}
+		if record, ok, _ := q.parse(line, true); ok && record != nil && (q.width == 0 || len(record) == q.width) {
+			return record, nil
+		}
+		return q.split(line), nil
+	}
+}
+
+// record notes the width of the first record, against which lazily parsed repairs are checked.
+func (q *quoteRepairReader) record(record []string) []string {
+	if q.width == 0 {
+		q.width = len(record)
+	}
+	return record
+}
+
+// nextLine returns the next physical line without its line ending.
+func (q *quoteRepairReader) nextLine() (string, error) {
+	if len(q.pending) > 0 {
+		line := q.pending[0]
+		q.pending = q.pending[1:]
+		return line, nil
+	}
+	line, err := q.r.ReadString('\n')
+	if line == "" && err != nil {
+		return "", err
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// parseMultiLine joins lines to line until the text parses as one record. The joined lines are kept for later when
+// no such record is found.
+func (q *quoteRepairReader) parseMultiLine(line string) ([]string, bool) {
+	text := line
+	var joined []string
+	for len(joined) < repairMaxLines {
+		next, err := q.nextLine()
+		if err != nil {
+			break
+		}
+		joined = append(joined, next)
+		text += "\n" + next
+		if strings.Count(text, `"`)%2 == 1 {
+			continue
+		}
+		if record, ok, _ := q.parse(text, q.options.LazyQuotes); ok && record != nil {
+			return record, true
+		}
+		break
+	}
+	q.pending = append(joined, q.pending...)
+	return nil, false
+}
+
+// parse parses text as a single record. ok is false when text does not parse or holds more than one record, and
+// record is nil for comment and blank lines.
+func (q *quoteRepairReader) parse(text string, lazy bool) (record []string, ok bool, err error) {
+	cr := newCSVReader(q.options, strings.NewReader(text))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = lazy
+	record, err = cr.Read()
+	if err == io.EOF {
+		return nil, true, nil
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if _, err := cr.Read(); err != io.EOF {
+		return nil, false, nil
+	}
+	return record, true, nil
+}
+
+// split splits line on the separator, ignoring quoting, and strips one outer quote from each end of every field.
+func (q *quoteRepairReader) split(line string) []string {
+	sep := q.options.Separator
+	if sep == 0 {
+		sep = ','
+	}
+	fields := strings.Split(line, string(sep))
+	for i, field := range fields {
+		field = strings.TrimSuffix(strings.TrimPrefix(field, `"`), `"`)
+		fields[i] = strings.ReplaceAll(field, `""`, `"`)
+	}
+	return fields
+}
+
+// quoteScanReader wraps a csv.Reader and keeps the raw input of the current record so that quoted empty fields ("")
+// can be told apart from bare empty ones, which encoding/csv returns identically. Raw lines before the last record
+// are discarded as reading proceeds.
+type quoteScanReader struct {
+	r      *csv.Reader
+	raw    []byte // raw input starting at line base
+	base   int    // line number of raw[0], numbered from 1 as by csv.Reader.FieldPos
+	quoted []bool // quoted[i] reports whether empty field i of the last record was quoted
+}
+
+func newQuoteScanReader(options *Options, rd io.Reader) *quoteScanReader {
+	r := &quoteScanReader{base: 1}
+	r.r = newCSVReader(options, io.TeeReader(rd, r))
+	return r
+}
+
+// Write appends input read by the csv.Reader to the raw buffer.
+func (r *quoteScanReader) Write(p []byte) (int, error) {
+	r.raw = append(r.raw, p...)
+	return len(p), nil
+}
+
+func (r *quoteScanReader) Read() ([]string, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		r.quoted = nil
+		return record, err
+	}
+
+	r.quoted = make([]bool, len(record))
+	for i := range record {
+		if record[i] != "" {
+			continue
+		}
+		line, col := r.r.FieldPos(i)
+		if off := r.lineOffset(line); off >= 0 && off+col-1 < len(r.raw) {
+			r.quoted[i] = r.raw[off+col-1] == '"'
+		}
+	}
+
+	if line, _ := r.r.FieldPos(len(record) - 1); line > r.base {
+		r.raw = r.raw[r.lineOffset(line):]
+		r.base = line
+	}
+	return record, nil
+}
+
+// lineOffset returns the offset in raw of the start of line, or -1 when line is not buffered.
+func (r *quoteScanReader) lineOffset(line int) int {
+	off := 0
+	for n := r.base; n < line; n++ {
+		i := bytes.IndexByte(r.raw[off:], '\n')
+		if i < 0 {
+			return -1
+		}
+		off += i + 1
+	}
+	return off
+}
+
+// quotedFields returns which empty fields of the last record read by r were quoted, or nil when r does not track
+// quoting. For COPY input every field but a null one counts as quoted, so empty strings are kept as values.
+func quotedFields(r recordReader) []bool {
+	switch r := r.(type) {
+	case *commentHeaderOnlyReader:
+		return quotedFields(r.r)
+	case *quoteScanReader:
+		return r.quoted
+	case *escapeReader:
+		if !r.copyFormat {
+			return nil
+		}
+		values := make([]bool, len(r.nulls))
+		for i, null := range r.nulls {
+			values[i] = !null
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package csv
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type pair struct {
+	A string
+	B string
+}
+
+func TestStringSeparator(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		content string
+		want    []pair
+	}{
+		{"pipe pair", &Options{StringSeparator: "||"}, "A||B\na||b\nc||d\n", []pair{{"a", "b"}, {"c", "d"}}},
+		{"double colon", &Options{StringSeparator: "::"}, "A::B\na::b\n", []pair{{"a", "b"}}},
+		{"single rune", &Options{StringSeparator: ";"}, "A;B\na;b\n", []pair{{"a", "b"}}},
+		{"quoted separator", &Options{StringSeparator: "||"}, "A||B\n\"a||x\"||b\n", []pair{{"a||x", "b"}}},
+		{"quoted newline and quote", &Options{StringSeparator: "||"}, "A||B\n\"a\nx\"\"\"||b\n", []pair{{"a\nx\"", "b"}}},
+		{"literal quote", &Options{StringSeparator: "||"}, "A||B\na\"x||b\n", []pair{{"a\"x", "b"}}},
+		{"comments", &Options{StringSeparator: "||", Comment: '#'}, "A||B\n#x||1\na||b\n", []pair{{"a", "b"}}},
+		{"comment header only", &Options{StringSeparator: "||", Comment: '#', CommentHeaderOnly: true}, "# c\nA||B\n#x||1\n#y||2\n", []pair{{"#x", "1"}, {"#y", "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[pair](tt.options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(*got[i], tt.want[i]) {
+					t.Errorf("row %d = %+v, want %+v", i, *got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStringSeparatorUnterminatedQuote(t *testing.T) {
+	if _, err := ProcessCSV[pair](&Options{StringSeparator: "||"}, "A||B\n\"a||b\n"); err == nil {
+		t.Fatal("expected an error for an unterminated quoted field")
+	}
+}
+
+func TestRepairQuotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		content  string
+		want     []pair
+		repaired int
+	}{
+		{"clean", Options{}, "A,B\na,b\n", []pair{{"a", "b"}}, 0},
+		{"stray quote in unquoted field", Options{}, "A,B\na\"x,b\n", []pair{{"a\"x", "b"}}, 1},
+		{"text after closing quote", Options{}, "A,B\n\"1\"x,2\n", []pair{{"1\"x", "2"}}, 1},
+		{"unterminated quote", Options{}, "A,B\n\"a,b\nc,d\n", []pair{{"a", "b"}, {"c", "d"}}, 1},
+		{"multiline quoted field", Options{}, "A,B\n\"a\nx\",b\n", []pair{{"a\nx", "b"}}, 0},
+		{"comment header only", Options{Comment: '#', CommentHeaderOnly: true}, "# c\nA,B\n#x,1\n#y,2\n", []pair{{"#x", "1"}, {"#y", "2"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.RepairQuotes = true
+			options.Stats = &ProcessStats{}
+			got, err := ProcessCSV[pair](&options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(*got[i], tt.want[i]) {
+					t.Errorf("row %d = %+v, want %+v", i, *got[i], tt.want[i])
+				}
+			}
+			if options.Stats.RepairedRows != tt.repaired {
+				t.Errorf("RepairedRows = %d, want %d", options.Stats.RepairedRows, tt.repaired)
+			}
+		})
+	}
+}
+
+func TestStripBOM(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"\xef\xbb\xbfA,B", "A,B"},
+		{"A,B", "A,B"},
+		{"", ""},
+		{"\xef\xbb", "\xef\xbb"},
+		{"A\xef\xbb\xbf", "A\xef\xbb\xbf"},
+		{"\xef\xbb\xbf\xef\xbb\xbfA", "\xef\xbb\xbfA"},
+	}
+	for _, tt := range tests {
+		if got := StripBOM(tt.in); got != tt.want {
+			t.Errorf("StripBOM(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadSkipsBOM(t *testing.T) {
+	got, err := ProcessCSV[pair](nil, "\xef\xbb\xbfA,B\na,b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || *got[0] != (pair{"a", "b"}) {
+		t.Errorf("got %+v, want the BOM skipped before the header", got)
+	}
+}
+
+func TestWhitespaceDelimitedLongLine(t *testing.T) {
+	long := strings.Repeat("x", 70*1024)
+	got, err := ProcessCSV[pair](&Options{WhitespaceDelimited: true}, "A B\n"+long+" b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].A != long || got[0].B != "b" {
+		t.Errorf("long line not read whole")
+	}
+}
+
+func TestRecordSeparatorLongRecord(t *testing.T) {
+	long := strings.Repeat("x", 70*1024)
+	got, err := ProcessCSV[pair](&Options{RecordSeparator: ';'}, "A,B;"+long+",b;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].A != long || got[0].B != "b" {
+		t.Errorf("long record not read whole")
+	}
+}
+
+func TestEscapeChar(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		content string
+		want    []pair
+	}{
+		{"escaped separator", &Options{EscapeChar: '\\'}, "A,B\na\\,x,b\n", []pair{{"a,x", "b"}}},
+		{"escaped newline", &Options{EscapeChar: '\\'}, "A,B\na\\nx,b\n", []pair{{"a\nx", "b"}}},
+		{"escaped line break", &Options{EscapeChar: '\\'}, "A,B\na\\\nx,b\n", []pair{{"a\nx", "b"}}},
+		{"escaped escape", &Options{EscapeChar: '\\'}, "A,B\na\\\\,b\n", []pair{{"a\\", "b"}}},
+		{"literal quotes", &Options{EscapeChar: '\\'}, "A,B\n\"a\",b\"\n", []pair{{"\"a\"", "b\""}}},
+		{"other separator", &Options{EscapeChar: '\\', Separator: '\t'}, "A\tB\na\\\tx\tb\n", []pair{{"a\tx", "b"}}},
+		{"comments", &Options{EscapeChar: '\\', Comment: '#'}, "A,B\n#x\\,y\na,b\n", []pair{{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[pair](tt.options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(*got[i], tt.want[i]) {
+					t.Errorf("row %d = %+v, want %+v", i, *got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := ProcessCSV[pair](&Options{EscapeChar: '\\'}, "A,B\na,b\\"); err == nil {
+		t.Error("expected an error for an escape character at end of input")
+	}
+}
+
+func TestCommentPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		content string
+		want    []pair
+	}{
+		{"dashes", &Options{CommentPrefix: "--"}, "-- dump\nA,B\n-- rows\na,b\n--\nc,d\n", []pair{{"a", "b"}, {"c", "d"}}},
+		{"single dash kept", &Options{CommentPrefix: "--"}, "A,B\n-1,b\n", []pair{{"-1", "b"}}},
+		{"slashes", &Options{CommentPrefix: "//"}, "// x\nA,B\na,b\n// y", []pair{{"a", "b"}}},
+		{"with Comment", &Options{CommentPrefix: "--", Comment: '#'}, "# x\n-- y\nA,B\na,b\n", []pair{{"a", "b"}}},
+		{"CRLF", &Options{CommentPrefix: "--"}, "-- x\r\nA,B\r\na,b\r\n", []pair{{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[pair](tt.options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if *got[i] != tt.want[i] {
+					t.Errorf("row %d = %+v, want %+v", i, *got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMaxLineBytes(t *testing.T) {
+	huge := strings.Repeat("x", 1<<20)
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"within limit", "A,B\na,b\n\"quoted\nfield\",c\n", ""},
+		{"long quoted records", "A,B\n\"" + strings.Repeat("y", 900) + "\",b\n\"" + strings.Repeat("z", 900) + "\",c\n", ""},
+		{"unterminated quote", "A,B\na,b\nc,d\n\"" + huge + "\n" + huge + "\n", "record starting on line 4 exceeds 1024 bytes: possible unterminated quote"},
+		{"long line", "A,B\n" + huge + ",b\n", "record starting on line 2 exceeds 1024 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProcessCSV[pair](&Options{MaxLineBytes: 1024}, tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ProcessCSV[pair](nil, "A,B\n"+huge+",b\n"); err != nil {
+		t.Errorf("long line rejected without MaxLineBytes: %v", err)
+	}
+}
+
+func TestMaxLineBytesLiteralQuotes(t *testing.T) {
+	rows := strings.Repeat("a,b\n", 500)
+	tests := []struct {
+		name    string
+		options *Options
+		content string
+	}{
+		{"bare quote with LazyQuotes", &Options{LazyQuotes: true}, "A,B\ntv,27\" screen\n" + rows},
+		{"escaped quote with EscapeChar", &Options{EscapeChar: '\\'}, "A,B\n\\\"x,27 screen\n" + rows},
+		{"doubled quotes in quoted field", &Options{}, "A,B\n\"a \"\"b\"\"\",c\n" + rows},
+		{"quoted field after leading space", &Options{TrimLeadingSpace: true}, "A,B\na, \"b\nc\"\n" + rows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := *tt.options
+			options.MaxLineBytes = 1024
+			got, err := ProcessCSV[pair](&options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 501 {
+				t.Errorf("got %d rows, want 501", len(got))
+			}
+		})
+	}
+}
+
+func TestQuotedEmptyAsValue(t *testing.T) {
+	type optional struct {
+		A *string
+		B *string
+	}
+	// describe renders each field as nil or its quoted value.
+	describe := func(rows []*optional) []string {
+		var got []string
+		for _, row := range rows {
+			for _, p := range []*string{row.A, row.B} {
+				if p == nil {
+					got = append(got, "nil")
+				} else {
+					got = append(got, strconv.Quote(*p))
+				}
+			}
+		}
+		return got
+	}
+
+	tests := []struct {
+		name    string
+		options *Options
+		content string
+		want    []string
+	}{
+		{"quoted and bare empty", &Options{QuotedEmptyAsValue: true}, "A,B\n\"\",\n,\"\"\n", []string{`""`, "nil", "nil", `""`}},
+		{"option off", &Options{}, "A,B\n\"\",\n", []string{"nil", "nil"}},
+		{"quoted field spanning lines", &Options{QuotedEmptyAsValue: true}, "A,B\n\"x\ny\",\"\"\n,\"\"\n\"\",\"a\n\nb\"\n,\n", []string{`"x\ny"`, `""`, "nil", `""`, `""`, `"a\n\nb"`, "nil", "nil"}},
+		{"leading space", &Options{QuotedEmptyAsValue: true, TrimLeadingSpace: true}, "A,B\n  \"\",  \n", []string{`""`, "nil"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ProcessCSV[optional](tt.options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
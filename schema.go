@@ -0,0 +1,91 @@
+package csv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// Schema describes how CSV headers map to struct fields, so the mapping can be kept outside the code. It is usually
+// loaded from JSON with LoadSchema:
+//
+//	{
+//	  "columns": [
+//	    {"header": "e-mail", "field": "Email"},
+//	    {"header": "score", "field": "Stats.Score", "type": "float64"}
+//	  ]
+//	}
+//
+// field is a struct field name, dotted for fields of nested structs. type is optional; for empty interface fields it
+// names the type the cell is parsed into and takes the place of FieldTypeHints. Supported types are int, int8,
+// int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string, bool, time.Time and
+// time.Duration. Headers not listed are resolved as usual.
+type Schema struct {
+	Columns []SchemaColumn `json:"columns"`
+}
+
+// SchemaColumn maps one header to a struct field.
+type SchemaColumn struct {
+	Header string `json:"header"`         // Header is the CSV header
+	Field  string `json:"field"`          // Field is the struct field name, dotted for nested structs
+	Type   string `json:"type,omitempty"` // Type is the type parsed into an empty interface field
+}
+
+var schemaTypes = map[string]reflect.Type{
+	"int":           reflect.TypeOf(int(0)),
+	"int8":          reflect.TypeOf(int8(0)),
+	"int16":         reflect.TypeOf(int16(0)),
+	"int32":         reflect.TypeOf(int32(0)),
+	"int64":         reflect.TypeOf(int64(0)),
+	"uint":          reflect.TypeOf(uint(0)),
+	"uint8":         reflect.TypeOf(uint8(0)),
+	"uint16":        reflect.TypeOf(uint16(0)),
+	"uint32":        reflect.TypeOf(uint32(0)),
+	"uint64":        reflect.TypeOf(uint64(0)),
+	"float32":       reflect.TypeOf(float32(0)),
+	"float64":       reflect.TypeOf(float64(0)),
+	"string":        reflect.TypeOf(""),
+	"bool":          reflect.TypeOf(false),
+	"time.Time":     reflect.TypeOf(time.Time{}),
+	"time.Duration": reflect.TypeOf(time.Duration(0)),
+}
+
+// LoadSchema reads a JSON schema from r and validates it.
+func LoadSchema(r io.Reader) (*Schema, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var schema Schema
+	if err := dec.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("error reading schema: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i, column := range schema.Columns {
+		if column.Header == "" || column.Field == "" {
+			return nil, fmt.Errorf("schema column %d: header and field are required", i)
+		}
+		if seen[column.Header] {
+			return nil, fmt.Errorf("schema column %d: duplicate header %s", i, column.Header)
+		}
+		seen[column.Header] = true
+		if _, ok := schemaTypes[column.Type]; column.Type != "" && !ok {
+			return nil, fmt.Errorf("schema column %d: unsupported type %s", i, column.Type)
+		}
+	}
+	return &schema, nil
+}
+
+// column returns the schema column for header.
+func (s *Schema) column(header string) (SchemaColumn, bool) {
+	if s == nil {
+		return SchemaColumn{}, false
+	}
+	for _, column := range s.Columns {
+		if column.Header == header {
+			return column, true
+		}
+	}
+	return SchemaColumn{}, false
+}
@@ -0,0 +1,89 @@
+package csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    *Schema
+		wantErr string
+	}{
+		{
+			"valid",
+			`{"columns": [{"header": "e-mail", "field": "Email"}, {"header": "score", "field": "Stats.Score", "type": "float64"}]}`,
+			&Schema{Columns: []SchemaColumn{{Header: "e-mail", Field: "Email"}, {Header: "score", Field: "Stats.Score", Type: "float64"}}},
+			"",
+		},
+		{"malformed", `{"columns": [`, nil, "error reading schema"},
+		{"unknown key", `{"columns": [{"header": "a", "field": "A", "kind": "int"}]}`, nil, "error reading schema"},
+		{"missing header", `{"columns": [{"field": "A"}]}`, nil, "schema column 0: header and field are required"},
+		{"missing field", `{"columns": [{"header": "a"}]}`, nil, "schema column 0: header and field are required"},
+		{"duplicate header", `{"columns": [{"header": "a", "field": "A"}, {"header": "a", "field": "B"}]}`, nil, "schema column 1: duplicate header a"},
+		{"unsupported type", `{"columns": [{"header": "a", "field": "A", "type": "complex128"}]}`, nil, "schema column 0: unsupported type complex128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadSchema(strings.NewReader(tt.json))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaTypeHints(t *testing.T) {
+	type scores struct {
+		Score float64
+	}
+	type row struct {
+		Email string
+		Value any
+		Stats scores
+	}
+	schema, err := LoadSchema(strings.NewReader(`{"columns": [
+		{"header": "e-mail", "field": "Email"},
+		{"header": "value", "field": "Value", "type": "int"},
+		{"header": "score", "field": "Stats.Score"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		schema  *Schema
+		content string
+		want    any
+	}{
+		{"hinted", schema, "e-mail,value,score\na@b.c,42,1.5\n", 42},
+		{"unhinted", nil, "Email,Value\na@b.c,42\n", "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[row](&Options{Schema: tt.schema}, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Email != "a@b.c" || got[0].Value != tt.want {
+				t.Errorf("got %+v, want Email a@b.c and Value %#v", got, tt.want)
+			}
+			if tt.schema != nil && got[0].Stats.Score != 1.5 {
+				t.Errorf("got Stats.Score %v, want 1.5", got[0].Stats.Score)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package csv
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sniffSampleSize is the number of bytes examined when detecting the separator.
+const sniffSampleSize = 64 * 1024
+
+// sniffLines is the maximum number of lines examined when detecting the separator.
+const sniffLines = 10
+
+// defaultDetectCandidates are the separators considered when Options.DetectCandidates is empty.
+var defaultDetectCandidates = []rune{',', ';', '\t'}
+
+// detectSeparator peeks at the start of br and returns the candidate that occurs outside quotes the same non-zero
+// number of times on every sampled line, preferring the most frequent. Blank lines and lines starting with comment or
+// commentPrefix, when set, are not sampled. It returns fallback when no candidate is consistent.
+func detectSeparator(br *bufio.Reader, candidates []rune, comment rune, commentPrefix string, fallback rune) rune {
+	if len(candidates) == 0 {
+		candidates = defaultDetectCandidates
+	}
+	sample, _ := br.Peek(sniffSampleSize)
+
+	var lines []string
+	for _, line := range strings.Split(string(sample), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if (comment != 0 && strings.HasPrefix(line, string(comment))) || (commentPrefix != "" && strings.HasPrefix(line, commentPrefix)) {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == sniffLines {
+			break
+		}
+	}
+	// The last line of a truncated sample may be incomplete.
+	if len(sample) == sniffSampleSize && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return fallback
+	}
+
+	best, bestCount := fallback, 0
+	for _, c := range candidates {
+		count := countOutsideQuotes(lines[0], c)
+		if count == 0 {
+			continue
+		}
+		consistent := true
+		for _, line := range lines[1:] {
+			if countOutsideQuotes(line, c) != count {
+				consistent = false
+				break
+			}
+		}
+		if consistent && count > bestCount {
+			best, bestCount = c, count
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, c rune) int {
+	count := 0
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == c && !quoted:
+			count++
+		}
+	}
+	return count
+}
+
+// sniffingReader returns a reader over rd and the separator detected from its first lines.
+func sniffingReader(options *Options, rd io.Reader) (io.Reader, rune) {
+	fallback := options.Separator
+	if fallback == 0 {
+		fallback = ','
+	}
+	br := bufio.NewReaderSize(rd, sniffSampleSize)
+	return br, detectSeparator(br, options.DetectCandidates, options.Comment, options.CommentPrefix, fallback)
+}
@@ -0,0 +1,44 @@
+package csv
+
+import (
+	"testing"
+)
+
+func TestAutoDetectSeparator(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []rune
+		content    string
+		want       pair
+	}{
+		{"comma", nil, "A,B\na,b\n", pair{"a", "b"}},
+		{"semicolon", nil, "A;B\na,1;b\n", pair{"a,1", "b"}},
+		{"tab", nil, "A\tB\na\tb\n", pair{"a", "b"}},
+		{"pipe excluded", []rune{',', ';'}, "A,B\na|x|y,b|z|w\n", pair{"a|x|y", "b|z|w"}},
+		{"pipe allowed", []rune{',', '|'}, "A|B\na|b\n", pair{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &Options{AutoDetectSeparator: true, DetectCandidates: tt.candidates}
+			got, err := ProcessCSV[pair](options, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || *got[0] != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoDetectSeparatorSkipsComments(t *testing.T) {
+	content := "# generated, by, tool\n// exported, today\nA;B\na,1;b\n"
+	options := &Options{AutoDetectSeparator: true, Comment: '#', CommentPrefix: "//"}
+	got, err := ProcessCSV[pair](options, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (pair{"a,1", "b"}); len(got) != 1 || *got[0] != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package csv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ColumnStats holds simple statistics for one column, gathered from the raw records.
+type ColumnStats struct {
+	Count    int    // Count is the number of cells read
+	Empty    int    // Empty is the number of empty cells
+	Distinct int    // Distinct is the number of distinct values, including the empty value
+	Min      string // Min is the smallest non-empty value, compared numerically when every non-empty value is a number
+	Max      string // Max is the largest non-empty value, compared like Min
+
+	distinct   map[string]struct{}
+	numeric    bool
+	minN, maxN float64
+	minV, maxV string // minV and maxV are the values holding minN and maxN
+	minS, maxS string
+	seen       bool
+}
+
+// ProcessCSVWithStats processes CSV input like ProcessCSV and also returns statistics for each column keyed by header.
+func ProcessCSVWithStats[T any](options *Options, content string) ([]*T, map[string]ColumnStats, error) {
+	stats := map[string]*ColumnStats{}
+	ts, err := processReader(options, strings.NewReader(content), processHooks[T]{
+		record: func(headers, record []string) {
+			for i, value := range record {
+				if i >= len(headers) {
+					break
+				}
+				cs, ok := stats[headers[i]]
+				if !ok {
+					cs = &ColumnStats{distinct: map[string]struct{}{}, numeric: true}
+					stats[headers[i]] = cs
+				}
+				cs.add(value)
+			}
+		},
+	})
+	if _, collected := err.(RowErrors); err != nil && !collected {
+		return nil, nil, err
+	}
+
+	result := make(map[string]ColumnStats, len(stats))
+	for header, cs := range stats {
+		cs.Distinct = len(cs.distinct)
+		if cs.seen {
+			cs.Min, cs.Max = cs.minS, cs.maxS
+			if cs.numeric {
+				cs.Min, cs.Max = cs.minV, cs.maxV
+			}
+		}
+		cs.distinct = nil
+		result[header] = *cs
+	}
+	return ts, result, err
+}
+
+func (cs *ColumnStats) add(value string) {
+	cs.Count++
+	cs.distinct[value] = struct{}{}
+	if value == "" {
+		cs.Empty++
+		return
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		cs.numeric = false
+	}
+	if !cs.seen {
+		cs.seen = true
+		cs.minS, cs.maxS = value, value
+		cs.minN, cs.maxN = n, n
+		cs.minV, cs.maxV = value, value
+		return
+	}
+	if value < cs.minS {
+		cs.minS = value
+	}
+	if value > cs.maxS {
+		cs.maxS = value
+	}
+	if cs.numeric {
+		if n < cs.minN {
+			cs.minN, cs.minV = n, value
+		}
+		if n > cs.maxN {
+			cs.maxN, cs.maxV = n, value
+		}
+	}
+}
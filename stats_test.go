@@ -0,0 +1,56 @@
+package csv
+
+import (
+	"testing"
+)
+
+func TestProcessCSVWithStats(t *testing.T) {
+	type row struct {
+		Name  string
+		Score string
+	}
+	content := "Name,Score\nbob,9\nalice,10\n,10\ncarol,\nbob,-2.5\n"
+	ts, stats, err := ProcessCSVWithStats[row](nil, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 5 {
+		t.Fatalf("got %d rows, want 5", len(ts))
+	}
+
+	tests := []struct {
+		header                 string
+		count, empty, distinct int
+		min, max               string
+	}{
+		{"Name", 5, 1, 4, "alice", "carol"},
+		{"Score", 5, 1, 4, "-2.5", "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			cs, ok := stats[tt.header]
+			if !ok {
+				t.Fatalf("no stats for %s", tt.header)
+			}
+			if cs.Count != tt.count || cs.Empty != tt.empty || cs.Distinct != tt.distinct {
+				t.Errorf("got count %d, empty %d, distinct %d, want %d, %d, %d", cs.Count, cs.Empty, cs.Distinct, tt.count, tt.empty, tt.distinct)
+			}
+			if cs.Min != tt.min || cs.Max != tt.max {
+				t.Errorf("got min %q, max %q, want %q, %q", cs.Min, cs.Max, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestProcessCSVWithStatsLexical(t *testing.T) {
+	type row struct {
+		Code string
+	}
+	_, stats, err := ProcessCSVWithStats[row](nil, "Code\n9\n10\nx7\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs := stats["Code"]; cs.Min != "10" || cs.Max != "x7" {
+		t.Errorf("got min %q, max %q, want lexical \"10\", \"x7\"", cs.Min, cs.Max)
+	}
+}
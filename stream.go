@@ -0,0 +1,33 @@
+package csv
+
+import (
+	"io"
+)
+
+// ProcessCSVStream processes CSV input from r in a new goroutine, sending each parsed struct and each row error as
+// they occur. Row errors are those ProcessCSV collects with CollectErrors and are sent as *RowError whether or not it
+// is set; the row is skipped and parsing continues. An error that ends parsing is sent last. Both channels are closed
+// once the input is exhausted or reading fails unrecoverably. The channels are unbuffered, so parsing proceeds only
+// as fast as values are received, and the caller must receive from both channels, for example in one select loop, to
+// avoid blocking the parser.
+func ProcessCSVStream[T any](options *Options, r io.Reader) (<-chan *T, <-chan error) {
+	out := make(chan *T)
+	errs := make(chan error)
+	options = normalizeOptions(options)
+
+	go func() {
+		defer close(out)
+		defer close(errs)
+
+		hooks := processHooks[T]{
+			onRecord: func(_ int, v *T) { out <- v },
+			onError:  func(err *RowError) { errs <- err },
+			discard:  true,
+		}
+		if _, err := processRecords(options, newRecordReader(options, r), hooks); err != nil {
+			errs <- err
+		}
+	}()
+
+	return out, errs
+}
@@ -0,0 +1,84 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type streamRow struct {
+	Row  int `csv:",rownum"`
+	Name string
+	N    int
+}
+
+// drain receives from both channels until they are closed.
+func drain[T any](out <-chan *T, errs <-chan error) ([]*T, []error) {
+	var ts []*T
+	var es []error
+	for out != nil || errs != nil {
+		select {
+		case t, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			ts = append(ts, t)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			es = append(es, err)
+		}
+	}
+	return ts, es
+}
+
+func TestProcessCSVStream(t *testing.T) {
+	options := &Options{
+		Deduplicate: true,
+		Validate:    true,
+		Validator: func(v interface{}) error {
+			if v.(*streamRow).Name == "bad" {
+				return errors.New("bad name")
+			}
+			return nil
+		},
+		StopFunc: func(headers, record []string) bool { return record[0] == "STOP" },
+	}
+	content := "Name,N\nx,1\nx,1\nbad,2\ny,z\ny,3\nSTOP,4\nz,5\n"
+	ts, errs := drain(ProcessCSVStream[streamRow](options, strings.NewReader(content)))
+
+	var got []string
+	for _, v := range ts {
+		got = append(got, fmt.Sprintf("%d:%s:%d", v.Row, v.Name, v.N))
+	}
+	if want := "0:x:1 4:y:3"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	for i, row := range []int{3, 4} {
+		var rowErr *RowError
+		if !errors.As(errs[i], &rowErr) || rowErr.Row != row {
+			t.Errorf("error %d = %v, want a row error for row %d", i, errs[i], row)
+		}
+	}
+}
+
+func TestProcessCSVStreamFatal(t *testing.T) {
+	ts, errs := drain(ProcessCSVStream[streamRow](nil, strings.NewReader("Name,Unknown\nx,1\n")))
+	if len(ts) != 0 || len(errs) != 1 {
+		t.Fatalf("got %d rows and errors %v, want an unknown field error only", len(ts), errs)
+	}
+}
+
+func TestProcessCSVStreamEmpty(t *testing.T) {
+	ts, errs := drain(ProcessCSVStream[streamRow](nil, strings.NewReader("")))
+	if len(ts) != 0 || len(errs) != 0 {
+		t.Fatalf("got %d rows and errors %v, want none", len(ts), errs)
+	}
+}
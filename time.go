@@ -0,0 +1,114 @@
+package csv
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// namedLayouts maps preset names accepted in Options.TimeLayouts to their time package layouts. DateTime, DateOnly
+// and TimeOnly are spelled out since the time package only defines them from Go 1.20.
+var namedLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+	"YearMonth":   "2006-01",
+	"YearOnly":    "2006",
+}
+
+// autoDetectLayouts are the layouts AutoDetectTimeFormat tries in order.
+var autoDetectLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// timeLayout returns the layout for a preset name, or layout itself when it is not a known name.
+func timeLayout(layout string) string {
+	if l, ok := namedLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+// parseTime parses s with each of Options.TimeLayouts in order and returns the first success. RFC3339 is used when
+// no layouts are configured. Values without a zone are interpreted in Options.TimeLocation, or UTC when it is nil.
+// Components missing from a partial layout such as "2006" or "2006-01" take their earliest value, so "2024-03"
+// parses as midnight on March 1, 2024. With AutoDetectTimeFormat and no layouts, an integer is read as Unix seconds
+// and other values are tried against autoDetectLayouts.
+func parseTime(options *Options, s string) (time.Time, error) {
+	layouts := options.TimeLayouts
+	if len(layouts) == 0 && options.AutoDetectTimeFormat {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC(), nil
+		}
+		layouts = autoDetectLayouts
+	}
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+
+	loc := options.TimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		t, err = time.ParseInLocation(timeLayout(layout), s, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	if len(layouts) == 1 {
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("parsing time %q: no layout of %q matched", s, layouts)
+}
+
+// composeDate builds a time from values holding the year and optionally the month, day, hour, minute and second, in
+// that order, in Options.TimeLocation or UTC when it is nil. Missing and empty components take their earliest value.
+func composeDate(options *Options, values []string) (time.Time, error) {
+	if len(values) > 6 {
+		return time.Time{}, fmt.Errorf("cannot compose a date from %d values", len(values))
+	}
+	parts := []int{0, 1, 1, 0, 0, 0}
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date component %q", value)
+		}
+		parts[i] = n
+	}
+
+	loc := options.TimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	t := time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, loc)
+	if t.Month() != time.Month(parts[1]) || t.Day() != parts[2] {
+		return time.Time{}, fmt.Errorf("invalid date %d-%02d-%02d", parts[0], parts[1], parts[2])
+	}
+	return t, nil
+}
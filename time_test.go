@@ -0,0 +1,189 @@
+package csv
+
+import (
+	"testing"
+	"time"
+)
+
+type dated struct {
+	Date time.Time
+}
+
+func TestTimeLayouts(t *testing.T) {
+	options := &Options{TimeLayouts: []string{"2006-01-02", "2006/01/02"}}
+	got, err := ProcessCSV[dated](options, "Date\n2024-03-05\n2024/03/06\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []time.Time{
+		time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+	} {
+		if !got[i].Date.Equal(want) {
+			t.Errorf("row %d = %v, want %v", i, got[i].Date, want)
+		}
+	}
+
+	if _, err := ProcessCSV[dated](options, "Date\n05.03.2024\n"); err == nil {
+		t.Error("expected an error when no layout matches")
+	}
+	got, err = ProcessCSV[dated](&Options{TimeLayouts: options.TimeLayouts, IgnoreFieldTypeErrors: true}, "Date\n05.03.2024\n")
+	if err != nil || !got[0].Date.IsZero() {
+		t.Errorf("with IgnoreFieldTypeErrors got %v, %v, want a zero time", got, err)
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	content := "Date\n2024-01-02 15:04:05\n"
+	layouts := []string{"2006-01-02 15:04:05"}
+
+	got, err := ProcessCSV[dated](&Options{TimeLayouts: layouts}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC); !got[0].Date.Equal(want) || got[0].Date.Location() != time.UTC {
+		t.Errorf("default location: got %v, want %v", got[0].Date, want)
+	}
+
+	got, err = ProcessCSV[dated](&Options{TimeLayouts: layouts, TimeLocation: ny}, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 20, 4, 5, 0, time.UTC); !got[0].Date.Equal(want) || got[0].Date.Location() != ny {
+		t.Errorf("New York: got %v, want %v", got[0].Date, want.In(ny))
+	}
+
+	got, err = ProcessCSV[dated](&Options{TimeLocation: ny}, "Date\n2024-01-02T15:04:05+01:00\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 14, 4, 5, 0, time.UTC); !got[0].Date.Equal(want) {
+		t.Errorf("embedded offset: got %v, want %v", got[0].Date, want)
+	}
+}
+
+func TestNamedTimeLayouts(t *testing.T) {
+	tests := []struct {
+		preset string
+		cell   string
+		want   time.Time
+	}{
+		{"RFC1123", "Tue, 05 Mar 2024 10:30:00 UTC", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"ANSIC", "Tue Mar  5 10:30:00 2024", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"DateOnly", "2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"DateTime", "2024-03-05 10:30:00", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"Kitchen", "3:04PM", time.Date(0, 1, 1, 15, 4, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			got, err := ProcessCSV[dated](&Options{TimeLayouts: []string{tt.preset}}, "Date\n\""+tt.cell+"\"\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got[0].Date.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got[0].Date, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartialDateLayouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		layouts []string
+		cell    string
+		want    time.Time
+	}{
+		{"year only", []string{"2006"}, "2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"year and month", []string{"2006-01"}, "2024-03", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"YearOnly preset", []string{"YearOnly"}, "1999", time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"YearMonth preset", []string{"YearMonth"}, "1999-12", time.Date(1999, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"full date first", []string{"2006-01-02", "2006-01", "2006"}, "2024-03", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[dated](&Options{TimeLayouts: tt.layouts}, "Date\n"+tt.cell+"\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got[0].Date.Equal(tt.want) || got[0].Date.Location() != time.UTC {
+				t.Errorf("got %v, want %v", got[0].Date, tt.want)
+			}
+		})
+	}
+}
+
+type optionalDated struct {
+	Date *time.Time
+}
+
+func TestTimePointerField(t *testing.T) {
+	tests := []struct {
+		name    string
+		layouts []string
+		cell    string
+		want    *time.Time
+	}{
+		{"empty", nil, "", nil},
+		{"RFC3339", nil, "2024-03-05T10:30:00Z", ptrTo(time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC))},
+		{"custom layout", []string{"02/01/2006"}, "05/03/2024", ptrTo(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC))},
+		{"custom layout empty", []string{"02/01/2006"}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[optionalDated](&Options{TimeLayouts: tt.layouts}, "Date\n\""+tt.cell+"\"\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch d := got[0].Date; {
+			case tt.want == nil && d != nil:
+				t.Errorf("got %v, want nil", *d)
+			case tt.want != nil && (d == nil || !d.Equal(*tt.want)):
+				t.Errorf("got %v, want %v", d, *tt.want)
+			}
+		})
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+func TestAutoDetectTimeFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		cell string
+		want time.Time
+	}{
+		{"RFC3339", "2024-03-05T10:30:00+02:00", time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC)},
+		{"RFC3339 fraction", "2024-03-05T10:30:00.25Z", time.Date(2024, 3, 5, 10, 30, 0, 250000000, time.UTC)},
+		{"DateTime", "2024-03-05 10:30:00", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"DateOnly", "2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"RFC1123", "Tue, 05 Mar 2024 10:30:00 GMT", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"RFC1123Z", "Tue, 05 Mar 2024 10:30:00 +0100", time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)},
+		{"Unix seconds", "1709634600", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"integer before layouts", "20240305", time.Unix(20240305, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCSV[dated](&Options{AutoDetectTimeFormat: true}, "Date\n\""+tt.cell+"\"\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got[0].Date.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got[0].Date, tt.want)
+			}
+		})
+	}
+
+	if _, err := ProcessCSV[dated](&Options{AutoDetectTimeFormat: true}, "Date\nMarch 5\n"); err == nil {
+		t.Error("expected an error when no format matches")
+	}
+	if _, err := ProcessCSV[dated](&Options{AutoDetectTimeFormat: true, TimeLayouts: []string{"01/02/2006"}}, "Date\n2024-03-05\n"); err == nil {
+		t.Error("expected TimeLayouts to take precedence over AutoDetectTimeFormat")
+	}
+}
@@ -0,0 +1,21 @@
+// Package validate wires struct validation with github.com/go-playground/validator into csv.Options. It is kept
+// separate so the core package does not depend on the validator.
+package validate
+
+import (
+	"github.com/go-playground/validator/v10"
+
+	csv "github.com/cbergoon/ccsv"
+)
+
+// Enable turns on Options.Validate and sets Options.Validator to check each record against its validate struct tags
+// (e.g. `validate:"email"`). A nil options is replaced with a new Options.
+func Enable(options *csv.Options) *csv.Options {
+	if options == nil {
+		options = &csv.Options{}
+	}
+	v := validator.New()
+	options.Validate = true
+	options.Validator = v.Struct
+	return options
+}
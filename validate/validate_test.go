@@ -0,0 +1,40 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+
+	csv "github.com/cbergoon/ccsv"
+)
+
+type user struct {
+	Email string `validate:"email"`
+	Age   int    `validate:"gte=0"`
+}
+
+func TestEnable(t *testing.T) {
+	got, err := csv.ProcessCSV[user](Enable(nil), "Email,Age\nada@example.com,36\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Email != "ada@example.com" {
+		t.Errorf("got %+v, want the valid row", got)
+	}
+}
+
+func TestEnableFailingEmail(t *testing.T) {
+	_, err := csv.ProcessCSV[user](Enable(nil), "Email,Age\nada@example.com,36\nnot-an-email,20\n")
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	if !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("error %q does not name the row", err)
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) || verrs[0].Field() != "Email" {
+		t.Errorf("error %v does not wrap the Email validation failure", err)
+	}
+}
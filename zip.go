@@ -0,0 +1,40 @@
+package csv
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ProcessZip processes every .csv entry of a zip archive and returns the structs of each entry keyed by its name.
+// Entries without a .csv extension are skipped.
+func ProcessZip[T any](options *Options, r io.ReaderAt, size int64) (map[string][]*T, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("error reading zip: %w", err)
+	}
+
+	results := map[string][]*T{}
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), ".csv") {
+			continue
+		}
+		ts, err := processZipEntry[T](options, file)
+		if err != nil {
+			return nil, fmt.Errorf("error processing %s: %w", file.Name, err)
+		}
+		results[file.Name] = ts
+	}
+	return results, nil
+}
+
+func processZipEntry[T any](options *Options, file *zip.File) ([]*T, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return processReader(options, rc, processHooks[T]{})
+}
@@ -0,0 +1,59 @@
+package csv
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestProcessZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	entries := []struct{ name, content string }{
+		{"a.csv", "A,B\na,b\n"},
+		{"notes.txt", "not,csv\n"},
+		{"data/", ""},
+		{"data/b.CSV", "A,B\nc,d\ne,f\n"},
+		{"data/readme.md", "# readme\n"},
+	}
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ProcessZip[pair](nil, bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := []string{"a.csv", "data/b.CSV"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got entries %v, want %v", names, want)
+	}
+	if rows := got["a.csv"]; len(rows) != 1 || *rows[0] != (pair{"a", "b"}) {
+		t.Errorf("got %+v for a.csv", rows)
+	}
+	if rows := got["data/b.CSV"]; len(rows) != 2 || *rows[0] != (pair{"c", "d"}) || *rows[1] != (pair{"e", "f"}) {
+		t.Errorf("got %+v for data/b.CSV", rows)
+	}
+}
+
+func TestProcessZipInvalid(t *testing.T) {
+	content := []byte("not a zip")
+	if _, err := ProcessZip[pair](nil, bytes.NewReader(content), int64(len(content))); err == nil {
+		t.Error("expected an error for input that is not a zip archive")
+	}
+}